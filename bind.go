@@ -0,0 +1,319 @@
+package velox
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimeLayouts are the layouts Bind tries, in order, for time fields without a layout tag
+var TimeLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	time.RFC3339,
+	"15:04",
+}
+
+var (
+	timeType         = reflect.TypeOf(time.Time{})
+	fileHeaderType   = reflect.TypeOf(&multipart.FileHeader{})
+	textUnmarshaller = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// Bind fills dst, a pointer to a struct, with the form, query and multipart data of a request,
+// and then validates it against its validate tags (see Validation.Struct). Fields are matched
+// by their form tag, or their json tag or name:
+//
+//	type Signup struct {
+//		Name     string                `form:"name" validate:"required"`
+//		Age      int                   `form:"age"`
+//		Birthday time.Time             `form:"birthday" layout:"02-01-2006"`
+//		Tags     []string              `form:"tags"`
+//		Address  Address               `form:"address"`
+//		Avatar   *multipart.FileHeader `form:"avatar"`
+//	}
+//
+// Nested structs read fields such as address.city, and slices of structs items.0.name. Values
+// that can't be converted are reported in the returned validation, keyed by field name; the
+// error is only set when the request itself can't be parsed, or when the tags can't be checked
+func (v *Velox) Bind(r *http.Request, dst interface{}) (*Validation, error) {
+	value := reflect.ValueOf(dst)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("velox: cannot bind into %T, it is not a pointer to a struct", dst)
+	}
+
+	var err error
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		err = r.ParseMultipartForm(v.config.uploads.maxUploadSize)
+	} else {
+		err = r.ParseForm()
+	}
+	if err != nil && !errors.Is(err, http.ErrNotMultipart) {
+		return nil, err
+	}
+
+	validation := v.ValidatorFor(r)
+
+	var files map[string][]*multipart.FileHeader
+	if r.MultipartForm != nil {
+		files = r.MultipartForm.File
+	}
+
+	validation.bindStruct(value.Elem(), "", r.Form, files)
+	return validation, validation.Struct(dst)
+}
+
+func (v *Validation) bindStruct(value reflect.Value, prefix string, form url.Values, files map[string][]*multipart.FileHeader) {
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		v.bindField(value.Field(i), prefix+name, field.Tag.Get("layout"), form, files)
+	}
+}
+
+func (v *Validation) bindField(value reflect.Value, key, layout string, form url.Values, files map[string][]*multipart.FileHeader) {
+	t := value.Type()
+
+	// uploaded files
+	switch {
+	case t == fileHeaderType:
+		if headers := files[key]; len(headers) > 0 {
+			value.Set(reflect.ValueOf(headers[0]))
+		}
+		return
+	case t.Kind() == reflect.Slice && t.Elem() == fileHeaderType:
+		if headers := files[key]; len(headers) > 0 {
+			value.Set(reflect.ValueOf(headers))
+		}
+		return
+	}
+
+	// nested structs
+	if isNestedStruct(t) {
+		v.bindStruct(value, key+".", form, files)
+		return
+	}
+	if t.Kind() == reflect.Ptr && isNestedStruct(t.Elem()) {
+		if !hasPrefix(form, files, key+".") {
+			return
+		}
+		if value.IsNil() {
+			value.Set(reflect.New(t.Elem()))
+		}
+		v.bindStruct(value.Elem(), key+".", form, files)
+		return
+	}
+	if t.Kind() == reflect.Slice && isNestedStruct(t.Elem()) {
+		indexes := sliceIndexes(form, files, key+".")
+		if len(indexes) == 0 {
+			return
+		}
+		slice := reflect.MakeSlice(t, indexes[len(indexes)-1]+1, indexes[len(indexes)-1]+1)
+		for _, i := range indexes {
+			v.bindStruct(slice.Index(i), fmt.Sprintf("%s.%d.", key, i), form, files)
+		}
+		value.Set(slice)
+		return
+	}
+
+	// slices of values, sent as repeated fields (tags=a&tags=b) or with brackets (tags[]=a)
+	if t.Kind() == reflect.Slice && t.Elem().Kind() != reflect.Uint8 && !reflect.PointerTo(t).Implements(textUnmarshaller) {
+		values, ok := form[key]
+		if !ok {
+			values, ok = form[key+"[]"]
+		}
+		if !ok {
+			return
+		}
+
+		slice := reflect.MakeSlice(t, 0, len(values))
+		for _, s := range values {
+			item := reflect.New(t.Elem()).Elem()
+			if !v.convert(item, key, s, layout) {
+				return
+			}
+			slice = reflect.Append(slice, item)
+		}
+		value.Set(slice)
+		return
+	}
+
+	values, ok := form[key]
+	if !ok || len(values) == 0 {
+		return
+	}
+	v.convert(value, key, values[0], layout)
+}
+
+// convert sets value from the string s, adding an error to the validation when s can't be
+// converted. It reports whether the conversion succeeded
+func (v *Validation) convert(value reflect.Value, key, s, layout string) bool {
+	t := value.Type()
+
+	if t.Kind() == reflect.Ptr {
+		if strings.TrimSpace(s) == "" {
+			return true
+		}
+		ptr := reflect.New(t.Elem())
+		if !v.convert(ptr.Elem(), key, s, layout) {
+			return false
+		}
+		value.Set(ptr)
+		return true
+	}
+
+	if reflect.PointerTo(t).Implements(textUnmarshaller) && t != timeType {
+		err := value.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+		if err != nil {
+			v.AddError(key, v.message("validation.invalid", "This field is invalid"))
+			return false
+		}
+		return true
+	}
+
+	if t.Kind() != reflect.String {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return true
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		value.SetString(s)
+
+	case reflect.Bool:
+		switch strings.ToLower(s) {
+		case "on", "yes":
+			value.SetBool(true)
+		case "off", "no":
+			value.SetBool(false)
+		default:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				v.AddError(key, v.message("validation.bool", "This field must be true or false"))
+				return false
+			}
+			value.SetBool(b)
+		}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if t == reflect.TypeOf(time.Duration(0)) {
+			d, err := time.ParseDuration(s)
+			if err != nil {
+				v.AddError(key, v.message("validation.duration", "This field must be a duration such as 1h30m"))
+				return false
+			}
+			value.SetInt(int64(d))
+			return true
+		}
+		n, err := strconv.ParseInt(s, 10, t.Bits())
+		if err != nil {
+			v.AddError(key, v.message("validation.int", "This field must be an integer"))
+			return false
+		}
+		value.SetInt(n)
+
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, t.Bits())
+		if err != nil {
+			v.AddError(key, v.message("validation.int", "This field must be an integer"))
+			return false
+		}
+		value.SetUint(n)
+
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, t.Bits())
+		if err != nil {
+			v.AddError(key, v.message("validation.float", "This field must be a floating point number"))
+			return false
+		}
+		value.SetFloat(n)
+
+	case reflect.Struct:
+		if t != timeType {
+			return true
+		}
+		layouts := TimeLayouts
+		if layout != "" {
+			layouts = []string{layout}
+		}
+		for _, l := range layouts {
+			if parsed, err := time.Parse(l, s); err == nil {
+				value.Set(reflect.ValueOf(parsed))
+				return true
+			}
+		}
+		v.AddError(key, v.message("validation.time", "This field must be a valid date"))
+		return false
+	}
+
+	return true
+}
+
+// isNestedStruct reports whether t is a struct that Bind fills field by field
+func isNestedStruct(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t != timeType && !reflect.PointerTo(t).Implements(textUnmarshaller)
+}
+
+// hasPrefix reports whether any submitted field starts with prefix
+func hasPrefix(form url.Values, files map[string][]*multipart.FileHeader, prefix string) bool {
+	for key := range form {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	for key := range files {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// sliceIndexes returns the sorted indexes submitted for a slice of structs, e.g. 0 and 1 for
+// items.0.name and items.1.name
+func sliceIndexes(form url.Values, files map[string][]*multipart.FileHeader, prefix string) []int {
+	seen := make(map[int]bool)
+	add := func(key string) {
+		if !strings.HasPrefix(key, prefix) {
+			return
+		}
+		index, _, _ := strings.Cut(strings.TrimPrefix(key, prefix), ".")
+		// indexes are capped so a request can't make us allocate a huge slice
+		if i, err := strconv.Atoi(index); err == nil && i >= 0 && i < 1000 {
+			seen[i] = true
+		}
+	}
+	for key := range form {
+		add(key)
+	}
+	for key := range files {
+		add(key)
+	}
+
+	indexes := make([]int, 0, len(seen))
+	for i := range seen {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
@@ -0,0 +1,234 @@
+package velox
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindAddress struct {
+	Street string `form:"street"`
+	City   string `form:"city" validate:"required"`
+}
+
+type bindItem struct {
+	Name     string `form:"name"`
+	Quantity int    `form:"quantity" validate:"min=1"`
+}
+
+type bindForm struct {
+	Name     string        `form:"name" validate:"required"`
+	Age      int           `form:"age"`
+	Price    float64       `form:"price"`
+	Count    uint8         `form:"count"`
+	Active   bool          `form:"active"`
+	Timeout  time.Duration `form:"timeout"`
+	Birthday time.Time     `form:"birthday" layout:"02-01-2006"`
+	Created  time.Time     `form:"created"`
+	Nick     *string       `form:"nick"`
+	Tags     []string      `form:"tags"`
+	Scores   []int         `form:"scores"`
+	Address  bindAddress   `form:"address"`
+	Billing  *bindAddress  `form:"billing"`
+	Items    []bindItem    `form:"items"`
+	Email    string        `json:"email"`
+	Ignored  string        `form:"-"`
+	internal string
+}
+
+func TestBind(t *testing.T) {
+	nick := "ana"
+
+	tests := []struct {
+		name     string
+		form     url.Values
+		expected bindForm
+		invalid  []string
+	}{
+		{
+			name: "values",
+			form: url.Values{
+				"name": {"Ana"}, "age": {" 30 "}, "price": {"9.5"}, "count": {"7"}, "active": {"on"},
+				"timeout": {"1h30m"}, "birthday": {"29-02-2024"}, "created": {"2024-01-02T15:04"},
+				"nick": {"ana"}, "email": {"ana@here.com"}, "Ignored": {"x"}, "internal": {"x"},
+				"address.city": {"Porto"},
+			},
+			expected: bindForm{
+				Name: "Ana", Age: 30, Price: 9.5, Count: 7, Active: true, Timeout: 90 * time.Minute,
+				Birthday: time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC),
+				Created:  time.Date(2024, 1, 2, 15, 4, 0, 0, time.UTC),
+				Nick:     &nick, Email: "ana@here.com", Address: bindAddress{City: "Porto"},
+			},
+		},
+		{
+			name: "empty values",
+			form: url.Values{"name": {"Ana"}, "age": {""}, "nick": {" "}, "address.city": {"Porto"}},
+			expected: bindForm{
+				Name: "Ana", Address: bindAddress{City: "Porto"},
+			},
+		},
+		{
+			name: "slices",
+			form: url.Values{"name": {"Ana"}, "tags": {"a", "b"}, "scores[]": {"1", "2"}, "address.city": {"Porto"}},
+			expected: bindForm{
+				Name: "Ana", Tags: []string{"a", "b"}, Scores: []int{1, 2}, Address: bindAddress{City: "Porto"},
+			},
+		},
+		{
+			name: "nested",
+			form: url.Values{
+				"name": {"Ana"}, "address.street": {"Rua"}, "address.city": {"Porto"},
+				"billing.city": {"Lisboa"},
+				"items.0.name": {"pen"}, "items.0.quantity": {"2"}, "items.2.name": {"ink"}, "items.2.quantity": {"1"},
+			},
+			expected: bindForm{
+				Name:    "Ana",
+				Address: bindAddress{Street: "Rua", City: "Porto"},
+				Billing: &bindAddress{City: "Lisboa"},
+				Items:   []bindItem{{"pen", 2}, {}, {"ink", 1}},
+			},
+			invalid: []string{"items.1.quantity"},
+		},
+		{
+			name: "conversion errors",
+			form: url.Values{
+				"name": {"Ana"}, "age": {"old"}, "price": {"cheap"}, "count": {"300"}, "active": {"maybe"},
+				"timeout": {"soon"}, "birthday": {"2024-02-29"}, "scores": {"1", "two"}, "address.city": {"Porto"},
+			},
+			expected: bindForm{Name: "Ana", Address: bindAddress{City: "Porto"}},
+			invalid:  []string{"active", "age", "birthday", "count", "price", "scores", "timeout"},
+		},
+		{
+			name:     "validation",
+			form:     url.Values{"billing.street": {"Rua"}},
+			expected: bindForm{Billing: &bindAddress{Street: "Rua"}},
+			invalid:  []string{"address.city", "billing.city", "name"},
+		},
+	}
+
+	v := newTestVelox()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.form.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			var dst bindForm
+			validation, err := v.Bind(r, &dst)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if !reflect.DeepEqual(dst, tt.expected) {
+				t.Errorf("expected %+v, got %+v", tt.expected, dst)
+			}
+			for _, key := range tt.invalid {
+				if validation.Errors[key] == "" {
+					t.Errorf("expected an error for %s", key)
+				}
+			}
+			if len(validation.Errors) != len(tt.invalid) {
+				t.Errorf("expected errors for %v, got %v", tt.invalid, validation.Errors)
+			}
+		})
+	}
+}
+
+func TestBind_Files(t *testing.T) {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	_ = mw.WriteField("title", "holidays")
+	for _, name := range []string{"a.jpg", "b.jpg"} {
+		part, _ := mw.CreateFormFile("photos", name)
+		_, _ = part.Write([]byte("photo"))
+	}
+	part, _ := mw.CreateFormFile("cover", "cover.jpg")
+	_, _ = part.Write([]byte("cover"))
+	_ = mw.Close()
+
+	r := httptest.NewRequest("POST", "/", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var dst struct {
+		Title  string                  `form:"title"`
+		Cover  *multipart.FileHeader   `form:"cover"`
+		Photos []*multipart.FileHeader `form:"photos"`
+		Other  *multipart.FileHeader   `form:"other"`
+	}
+
+	v := newTestVelox()
+	v.config.uploads.maxUploadSize = 1 << 20
+	if _, err := v.Bind(r, &dst); err != nil {
+		t.Fatal(err)
+	}
+
+	if dst.Title != "holidays" {
+		t.Errorf("expected the title to be bound, got %q", dst.Title)
+	}
+	if dst.Cover == nil || dst.Cover.Filename != "cover.jpg" {
+		t.Errorf("expected the cover to be bound, got %+v", dst.Cover)
+	}
+	if len(dst.Photos) != 2 || dst.Photos[1].Filename != "b.jpg" {
+		t.Errorf("expected two photos, got %+v", dst.Photos)
+	}
+	if dst.Other != nil {
+		t.Error("expected a file that wasn't sent to stay nil")
+	}
+}
+
+func TestBind_Errors(t *testing.T) {
+	v := newTestVelox()
+
+	tests := []struct {
+		name string
+		dst  interface{}
+		rule bool
+	}{
+		{"not a pointer", bindForm{}, false},
+		{"pointer to a map", &map[string]string{}, false},
+		{"nil", nil, false},
+		{"bad tag", &struct {
+			Name string `form:"name" validate:"required,shiny"`
+		}{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader("name=ana"))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+			_, err := v.Bind(r, tt.dst)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, ErrValidationRule) != tt.rule {
+				t.Errorf("expected wrapping ErrValidationRule to be %t, got %v", tt.rule, err)
+			}
+		})
+	}
+
+	r := httptest.NewRequest("POST", "/", strings.NewReader("name=%zz"))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if _, err := v.Bind(r, &bindForm{}); err == nil {
+		t.Error("expected an error for a form that can't be parsed")
+	}
+
+	r = httptest.NewRequest("POST", "/", strings.NewReader("--x--"))
+	r.Header.Set("Content-Type", "multipart/form-data")
+	v.config.uploads.maxUploadSize = 1 << 20
+	if _, err := v.Bind(r, &bindForm{}); err == nil {
+		t.Error("expected an error for a multipart body without a boundary")
+	}
+
+	r = httptest.NewRequest(http.MethodGet, "/?name=ana&address.city=Porto", nil)
+	validation, err := v.Bind(r, &bindForm{})
+	if err != nil || !validation.Valid() {
+		t.Errorf("expected the query string to be bound, got %v %v", err, validation.Errors)
+	}
+}
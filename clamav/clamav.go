@@ -0,0 +1,159 @@
+package clamav
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// ErrResponse is returned when clamd answers with an error, such as when a file is larger than its
+// StreamMaxLength
+var ErrResponse = errors.New("clamav: clamd returned an error")
+
+// Client scans files with clamd, sending them with the INSTREAM command
+type Client struct {
+	// Address is where clamd listens: host:port, the path of its Unix socket, or either of them
+	// prefixed with tcp:// or unix://
+	Address string
+	// Timeout bounds a whole scan; it defaults to a minute
+	Timeout time.Duration
+	// ChunkSize is the size of the chunks files are sent in; it defaults to 64KB
+	ChunkSize int
+}
+
+// Scan sends the file read from r to clamd, and returns the name of the threat found in it, or an
+// empty string when the file is clean
+func (c *Client) Scan(ctx context.Context, r io.Reader) (string, error) {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("zINSTREAM\x00"))
+	if err != nil {
+		return "", err
+	}
+
+	chunkSize := c.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = 64 << 10
+	}
+
+	buf := make([]byte, 4+chunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf[:4], uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				// clamd closes the connection once a stream is too large, after saying so
+				response, respErr := readResponse(conn)
+				if respErr == nil && response != "" {
+					return parseResponse(response)
+				}
+				return "", err
+			}
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return "", readErr
+		}
+	}
+
+	// a zero length chunk ends the stream
+	_, err = conn.Write([]byte{0, 0, 0, 0})
+	if err != nil {
+		return "", err
+	}
+
+	response, err := readResponse(conn)
+	if err != nil {
+		return "", err
+	}
+	return parseResponse(response)
+}
+
+// Ping checks that clamd is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte("zPING\x00"))
+	if err != nil {
+		return err
+	}
+
+	response, err := readResponse(conn)
+	if err != nil {
+		return err
+	}
+	if response != "PONG" {
+		return fmt.Errorf("%w: %s", ErrResponse, response)
+	}
+	return nil
+}
+
+func (c *Client) dial(ctx context.Context) (net.Conn, error) {
+	network, address := "tcp", c.Address
+	switch {
+	case strings.HasPrefix(address, "unix://"):
+		network, address = "unix", strings.TrimPrefix(address, "unix://")
+	case strings.HasPrefix(address, "tcp://"):
+		address = strings.TrimPrefix(address, "tcp://")
+	case strings.HasPrefix(address, "/"):
+		network = "unix"
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = time.Minute
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	_ = conn.SetDeadline(deadline)
+
+	return conn, nil
+}
+
+// readResponse reads a reply of clamd, which ends with a null byte since commands start with z
+func readResponse(conn net.Conn) (string, error) {
+	response, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && !(err == io.EOF && len(response) > 0) {
+		return "", err
+	}
+	return string(bytes.TrimRight(response, "\x00\n")), nil
+}
+
+// parseResponse reads the result of a scan, such as "stream: OK" or
+// "stream: Eicar-Test-Signature FOUND"
+func parseResponse(response string) (string, error) {
+	result := strings.TrimPrefix(response, "stream: ")
+
+	switch {
+	case result == "OK":
+		return "", nil
+	case strings.HasSuffix(result, " FOUND"):
+		return strings.TrimSuffix(result, " FOUND"), nil
+	}
+	return "", fmt.Errorf("%w: %s", ErrResponse, strings.TrimSuffix(result, " ERROR"))
+}
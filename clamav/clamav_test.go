@@ -0,0 +1,60 @@
+package clamav
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestClient_Scan(t *testing.T) {
+	tests := []struct {
+		name      string
+		address   string
+		chunkSize int
+		data      string
+		signature string
+		err       error
+	}{
+		{"clean over tcp", tcpAddress, 0, "hello world", "", nil},
+		{"clean over tcp://", "tcp://" + tcpAddress, 0, "hello world", "", nil},
+		{"infected over tcp", tcpAddress, 0, eicar, "Eicar-Test-Signature", nil},
+		{"infected over unix", unixAddress, 0, eicar, "Eicar-Test-Signature", nil},
+		{"infected over unix://", "unix://" + unixAddress, 0, eicar, "Eicar-Test-Signature", nil},
+		{"small chunks", tcpAddress, 7, "some text " + eicar + " more text", "Eicar-Test-Signature", nil},
+		{"empty", tcpAddress, 0, "", "", nil},
+		{"too large", tcpAddress, 0, strings.Repeat("a", maxStream+1), "", ErrResponse},
+	}
+
+	for _, e := range tests {
+		c := &Client{Address: e.address, ChunkSize: e.chunkSize}
+		signature, err := c.Scan(context.Background(), bytes.NewReader([]byte(e.data)))
+
+		if e.err != nil {
+			if !errors.Is(err, e.err) {
+				t.Errorf("%s: expected %v, got %v", e.name, e.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s", e.name, err)
+			continue
+		}
+		if signature != e.signature {
+			t.Errorf("%s: expected %q, got %q", e.name, e.signature, signature)
+		}
+	}
+}
+
+func TestClient_Ping(t *testing.T) {
+	c := &Client{Address: unixAddress}
+	if err := c.Ping(context.Background()); err != nil {
+		t.Error(err)
+	}
+
+	c = &Client{Address: "unix://" + testDir + "/missing.sock"}
+	if err := c.Ping(context.Background()); err == nil {
+		t.Error("expected an error when clamd is not listening")
+	}
+}
@@ -0,0 +1,105 @@
+package clamav
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// eicar is the standard test file every antivirus reports
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// maxStream is the StreamMaxLength of the fake clamd
+const maxStream = 1 << 20
+
+var testDir string
+var tcpAddress string
+var unixAddress string
+
+func TestMain(m *testing.M) {
+	var err error
+	testDir, err = os.MkdirTemp("", "velox-clamav")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tcpListener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		log.Fatal(err)
+	}
+	tcpAddress = tcpListener.Addr().String()
+	go serveClamd(tcpListener)
+
+	unixAddress = filepath.Join(testDir, "clamd.sock")
+	unixListener, err := net.Listen("unix", unixAddress)
+	if err != nil {
+		log.Fatal(err)
+	}
+	go serveClamd(unixListener)
+
+	code := m.Run()
+
+	_ = tcpListener.Close()
+	_ = unixListener.Close()
+	_ = os.RemoveAll(testDir)
+	os.Exit(code)
+}
+
+// serveClamd answers PING and INSTREAM commands like clamd, reporting the files that contain the
+// EICAR test string
+func serveClamd(listener net.Listener) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			r := bufio.NewReader(conn)
+
+			command, err := r.ReadString(0)
+			if err != nil {
+				return
+			}
+
+			switch command {
+			case "zPING\x00":
+				_, _ = conn.Write([]byte("PONG\x00"))
+			case "zINSTREAM\x00":
+				var data []byte
+				for {
+					var size uint32
+					if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					if len(data)+int(size) > maxStream {
+						_, _ = conn.Write([]byte("INSTREAM size limit exceeded. ERROR\x00"))
+						return
+					}
+					chunk := make([]byte, size)
+					if _, err := io.ReadFull(r, chunk); err != nil {
+						return
+					}
+					data = append(data, chunk...)
+				}
+
+				if bytes.Contains(data, []byte(eicar)) {
+					_, _ = conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+				} else {
+					_, _ = conn.Write([]byte("stream: OK\x00"))
+				}
+			default:
+				_, _ = conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			}
+		}()
+	}
+}
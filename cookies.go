@@ -0,0 +1,111 @@
+package velox
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FernandoJVideira/velox/session"
+)
+
+// baseCookie returns a cookie carrying the attributes configured in the .env file
+// (COOKIE_DOMAIN, COOKIE_PATH, COOKIE_SECURE, COOKIE_SAMESITE, COOKIE_HTTPONLY and
+// COOKIE_PARTITIONED). The session, CSRF and remember me cookies are all built from it
+func (v *Velox) baseCookie() http.Cookie {
+	secure, _ := strconv.ParseBool(v.config.cookie.secure)
+	sameSite := session.ParseSameSite(v.config.cookie.sameSite)
+	// browsers reject SameSite=None and partitioned cookies that are not secure
+	if partitioned, _ := strconv.ParseBool(v.config.cookie.partitioned); partitioned || sameSite == http.SameSiteNoneMode {
+		secure = true
+	}
+
+	path := v.config.cookie.path
+	if path == "" {
+		path = "/"
+	}
+
+	return http.Cookie{
+		Domain:   v.config.cookie.domain,
+		Path:     path,
+		Secure:   secure,
+		HttpOnly: strings.ToLower(v.config.cookie.httpOnly) != "false",
+		SameSite: sameSite,
+	}
+}
+
+// csrfCookie returns the base cookie of the CSRF token, which is SameSite=Strict unless
+// CSRF_COOKIE_SAMESITE says otherwise
+func (v *Velox) csrfCookie() http.Cookie {
+	cookie := v.baseCookie()
+	cookie.SameSite = http.SameSiteStrictMode
+	if v.config.cookie.csrfSameSite != "" {
+		cookie.SameSite = session.ParseSameSite(v.config.cookie.csrfSameSite)
+		if cookie.SameSite == http.SameSiteNoneMode {
+			cookie.Secure = true
+		}
+	}
+	return cookie
+}
+
+// NewCookie returns a cookie following the application's cookie policy. A zero expires
+// creates a browser session cookie, and a time in the past deletes the cookie
+func (v *Velox) NewCookie(name, value string, expires time.Time) *http.Cookie {
+	cookie := v.baseCookie()
+	cookie.Name = name
+	cookie.Value = value
+
+	if !expires.IsZero() {
+		cookie.Expires = expires
+		cookie.MaxAge = int(time.Until(expires).Seconds())
+		if cookie.MaxAge <= 0 {
+			cookie.MaxAge = -1
+		}
+	}
+
+	return &cookie
+}
+
+// partitionCookies adds the Partitioned attribute (CHIPS) to every cookie set further down the
+// middleware chain, since neither net/http nor scs can set it themselves
+func partitionCookies(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		pw := &partitionedWriter{ResponseWriter: w}
+		next.ServeHTTP(pw, r)
+		pw.partition()
+	})
+}
+
+type partitionedWriter struct {
+	http.ResponseWriter
+	done bool
+}
+
+func (p *partitionedWriter) WriteHeader(status int) {
+	p.partition()
+	p.ResponseWriter.WriteHeader(status)
+}
+
+func (p *partitionedWriter) Write(b []byte) (int, error) {
+	p.partition()
+	return p.ResponseWriter.Write(b)
+}
+
+func (p *partitionedWriter) Unwrap() http.ResponseWriter {
+	return p.ResponseWriter
+}
+
+// partition rewrites the Set-Cookie headers once, before they are sent to the client
+func (p *partitionedWriter) partition() {
+	if p.done {
+		return
+	}
+	p.done = true
+
+	cookies := p.Header()["Set-Cookie"]
+	for i, c := range cookies {
+		if !strings.Contains(strings.ToLower(c), "; partitioned") {
+			cookies[i] = c + "; Partitioned"
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package velox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBaseCookie(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   cookieConfig
+		expected http.Cookie
+	}{
+		{"defaults", cookieConfig{},
+			http.Cookie{Path: "/", HttpOnly: true, SameSite: http.SameSiteLaxMode}},
+		{"configured", cookieConfig{domain: "example.com", path: "/app", secure: "true", sameSite: "strict", httpOnly: "false"},
+			http.Cookie{Domain: "example.com", Path: "/app", Secure: true, SameSite: http.SameSiteStrictMode}},
+		{"samesite none", cookieConfig{sameSite: "none"},
+			http.Cookie{Path: "/", Secure: true, HttpOnly: true, SameSite: http.SameSiteNoneMode}},
+		{"partitioned", cookieConfig{partitioned: "true"},
+			http.Cookie{Path: "/", Secure: true, HttpOnly: true, SameSite: http.SameSiteLaxMode}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVelox()
+			v.config.cookie = tt.config
+
+			if got := v.baseCookie(); got.String() != tt.expected.String() || got.SameSite != tt.expected.SameSite {
+				t.Errorf("expected %v, got %v", tt.expected.String(), got.String())
+			}
+		})
+	}
+}
+
+func TestCSRFCookie(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   cookieConfig
+		sameSite http.SameSite
+		secure   bool
+	}{
+		{"strict by default", cookieConfig{sameSite: "lax"}, http.SameSiteStrictMode, false},
+		{"configured", cookieConfig{sameSite: "strict", csrfSameSite: "lax"}, http.SameSiteLaxMode, false},
+		{"none is secure", cookieConfig{csrfSameSite: "none"}, http.SameSiteNoneMode, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVelox()
+			v.config.cookie = tt.config
+
+			cookie := v.csrfCookie()
+			if cookie.SameSite != tt.sameSite || cookie.Secure != tt.secure {
+				t.Errorf("expected samesite %v and secure %v, got %v", tt.sameSite, tt.secure, cookie.String())
+			}
+		})
+	}
+}
+
+func TestNewCookie(t *testing.T) {
+	v := newTestVelox()
+	v.config.cookie = cookieConfig{domain: "example.com", secure: "true"}
+
+	cookie := v.NewCookie("remember", "token", time.Time{})
+	if cookie.Name != "remember" || cookie.Value != "token" || cookie.Domain != "example.com" || !cookie.Secure {
+		t.Errorf("expected the cookie to follow the policy, got %v", cookie.String())
+	}
+	if !cookie.Expires.IsZero() || cookie.MaxAge != 0 {
+		t.Errorf("expected a browser session cookie, got %v", cookie.String())
+	}
+
+	cookie = v.NewCookie("remember", "token", time.Now().Add(time.Hour))
+	if cookie.MaxAge < 3590 || cookie.MaxAge > 3600 || cookie.Expires.IsZero() {
+		t.Errorf("expected the cookie to last an hour, got %v", cookie.String())
+	}
+
+	cookie = v.NewCookie("remember", "", time.Now().Add(-time.Hour))
+	if cookie.MaxAge != -1 {
+		t.Errorf("expected an expired cookie to be deleted, got %v", cookie.String())
+	}
+}
+
+func TestPartitionCookies(t *testing.T) {
+	tests := []struct {
+		name    string
+		write   func(w http.ResponseWriter)
+		cookies int
+	}{
+		{"write", func(w http.ResponseWriter) { _, _ = w.Write([]byte("hello")) }, 2},
+		{"write header", func(w http.ResponseWriter) { w.WriteHeader(http.StatusNoContent) }, 2},
+		{"no body", func(w http.ResponseWriter) {}, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := partitionCookies(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.SetCookie(w, &http.Cookie{Name: "session", Value: "1", Secure: true})
+				w.Header().Add("Set-Cookie", "theme=dark; Secure; Partitioned")
+				tt.write(w)
+				// cookies set once the headers are sent never reach the client, others are partitioned too
+				http.SetCookie(w, &http.Cookie{Name: "late", Value: "1"})
+			}))
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+
+			cookies := w.Result().Header["Set-Cookie"]
+			if len(cookies) != tt.cookies {
+				t.Fatalf("expected %d cookies, got %v", tt.cookies, cookies)
+			}
+			for _, cookie := range cookies {
+				if strings.Count(strings.ToLower(cookie), "partitioned") != 1 {
+					t.Errorf("expected the cookie to be partitioned once, got %q", cookie)
+				}
+			}
+		})
+	}
+}
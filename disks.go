@@ -0,0 +1,155 @@
+package velox
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+	"github.com/FernandoJVideira/velox/filesystems/localfilesystem"
+	"github.com/FernandoJVideira/velox/filesystems/miniofilesystem"
+	"github.com/FernandoJVideira/velox/filesystems/s3filesystem"
+	"github.com/FernandoJVideira/velox/filesystems/sftpfilesystem"
+	"github.com/FernandoJVideira/velox/filesystems/webdavfilesystem"
+)
+
+// Disk returns the disk called name, or the default disk when name is empty. The error wraps
+// filesystems.ErrUnknownDisk when there is no such disk
+func (v *Velox) Disk(name string) (filesystems.FS, error) {
+	if v.Disks == nil {
+		return nil, fmt.Errorf("%w: %q", filesystems.ErrUnknownDisk, name)
+	}
+	return v.Disks.Get(name)
+}
+
+// createDisks sets up the disks listed in DISKS, e.g. DISKS=avatars,backups. Each disk is
+// configured by the variables starting with DISK_ and its upper case name, e.g. DISK_AVATARS_DRIVER=s3
+// and DISK_AVATARS_BUCKET=avatars, and DEFAULT_DISK names the default one, which is otherwise the
+// first. The memory driver is only available when filesystems/memfilesystem is imported. The
+// filesystems configured by the S3_, MINIO_, SFTP_, WEBDAV_ and LOCAL_ROOT variables are
+// added as the s3, minio, sftp, webdav and local disks, unless disks with those names exist
+func (v *Velox) createDisks() (*filesystems.Disks, error) {
+	disks := &filesystems.Disks{}
+
+	for _, name := range strings.Split(os.Getenv("DISKS"), ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		config := filesystems.DiskConfig{
+			Name:     name,
+			Options:  diskOptions(name),
+			RootPath: v.RootPath,
+		}
+		config.Driver = config.Options["driver"]
+
+		fs, err := filesystems.Open(config)
+		if err != nil {
+			return nil, err
+		}
+		disks.Add(name, fs)
+	}
+
+	v.createFileSystems(disks)
+
+	if os.Getenv("DEFAULT_DISK") != "" {
+		err := disks.SetDefault(os.Getenv("DEFAULT_DISK"))
+		if err != nil {
+			return nil, fmt.Errorf("DEFAULT_DISK: %w", err)
+		}
+	}
+
+	return disks, nil
+}
+
+// diskOptions returns the variables configuring the disk called name, by lower case name without
+// their prefix; hyphens in the disk's name are underscores in the variables
+func diskOptions(name string) map[string]string {
+	prefix := "DISK_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+	options := make(map[string]string)
+
+	for _, env := range os.Environ() {
+		key, value, ok := strings.Cut(env, "=")
+		if ok && strings.HasPrefix(key, prefix) {
+			options[strings.ToLower(strings.TrimPrefix(key, prefix))] = value
+		}
+	}
+	return options
+}
+
+// createFileSystems sets up the filesystems configured by the variables of each driver, and adds
+// them to disks and to v.FileSystems
+func (v *Velox) createFileSystems(disks *filesystems.Disks) {
+	v.FileSystems = make(map[string]interface{})
+
+	add := func(name string, fs filesystems.FS) {
+		if _, err := disks.Get(name); err != nil {
+			disks.Add(name, fs)
+		}
+	}
+
+	if os.Getenv("MINIO_SECRET") != "" {
+		useSSL := false
+		if strings.ToLower(os.Getenv("MINIO_USESSL")) == "true" {
+			useSSL = true
+		}
+
+		v.Minio = miniofilesystem.Minio{
+			Endpoint: os.Getenv("MINIO_ENDPOINT"),
+			Key:      os.Getenv("MINIO_KEY"),
+			Secret:   os.Getenv("MINIO_SECRET"),
+			UseSSL:   useSSL,
+			Region:   os.Getenv("MINIO_REGION"),
+			Bucket:   os.Getenv("MINIO_BUCKET"),
+		}
+		v.FileSystems["MINIO"] = v.Minio
+		add("minio", &v.Minio)
+	}
+
+	if os.Getenv("SFTP_HOST") != "" {
+		v.SFTP = sftpfilesystem.SFTP{
+			Host: os.Getenv("SFTP_HOST"),
+			User: os.Getenv("SFTP_USER"),
+			Pass: os.Getenv("SFTP_PASS"),
+			Port: os.Getenv("SFTP_PORT"),
+		}
+		v.FileSystems["SFTP"] = v.SFTP
+		add("sftp", &v.SFTP)
+	}
+
+	if os.Getenv("WEBDAV_HOST") != "" {
+		v.WebDAV = webdavfilesystem.WebDAV{
+			Host: os.Getenv("WEBDAV_HOST"),
+			User: os.Getenv("WEBDAV_USER"),
+			Pass: os.Getenv("WEBDAV_PASS"),
+		}
+		v.FileSystems["WEBDAV"] = v.WebDAV
+		add("webdav", &v.WebDAV)
+	}
+
+	if os.Getenv("S3_KEY") != "" {
+		v.S3 = s3filesystem.S3{
+			Key:      os.Getenv("S3_KEY"),
+			Secret:   os.Getenv("S3_SECRET"),
+			Region:   os.Getenv("S3_REGION"),
+			Endpoint: os.Getenv("S3_ENDPOINT"),
+			Bucket:   os.Getenv("S3_BUCKET"),
+		}
+		v.FileSystems["S3"] = v.S3
+		add("s3", &v.S3)
+	}
+
+	if os.Getenv("LOCAL_ROOT") != "" {
+		root := os.Getenv("LOCAL_ROOT")
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(v.RootPath, root)
+		}
+		v.Local = localfilesystem.Local{
+			Root: root,
+		}
+		v.FileSystems["LOCAL"] = v.Local
+		add("local", &v.Local)
+	}
+}
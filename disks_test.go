@@ -0,0 +1,102 @@
+package velox
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+	"github.com/FernandoJVideira/velox/filesystems/localfilesystem"
+	"github.com/FernandoJVideira/velox/filesystems/memfilesystem"
+)
+
+func TestDiskOptions(t *testing.T) {
+	t.Setenv("DISK_USER_FILES_DRIVER", "s3")
+	t.Setenv("DISK_USER_FILES_BUCKET", "files")
+	t.Setenv("DISK_USER_DRIVER", "local")
+
+	expected := map[string]string{"driver": "s3", "bucket": "files"}
+	if options := diskOptions("user-files"); !reflect.DeepEqual(options, expected) {
+		t.Errorf("expected %v, got %v", expected, options)
+	}
+	if options := diskOptions("missing"); len(options) != 0 {
+		t.Errorf("expected no options, got %v", options)
+	}
+}
+
+func TestCreateDisks(t *testing.T) {
+	for _, env := range []string{"MINIO_SECRET", "SFTP_HOST", "WEBDAV_HOST", "S3_KEY"} {
+		t.Setenv(env, "")
+	}
+	t.Setenv("DISKS", "scratch, avatars,")
+	t.Setenv("DISK_SCRATCH_DRIVER", "memory")
+	t.Setenv("DISK_AVATARS_DRIVER", "local")
+	t.Setenv("DISK_AVATARS_ROOT", "avatars")
+	t.Setenv("LOCAL_ROOT", "storage")
+	t.Setenv("DEFAULT_DISK", "")
+
+	v := newTestVelox()
+	v.RootPath = t.TempDir()
+
+	disks, err := v.createDisks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := disks.Names(); !reflect.DeepEqual(names, []string{"avatars", "local", "scratch"}) {
+		t.Errorf("expected the listed disks and the local filesystem, got %v", names)
+	}
+	if disks.Default() != "scratch" {
+		t.Errorf("expected the first disk to be the default, got %q", disks.Default())
+	}
+
+	if fs, _ := disks.Get("scratch"); reflect.TypeOf(fs) != reflect.TypeOf(&memfilesystem.Mem{}) {
+		t.Errorf("expected a memory disk, got %T", fs)
+	}
+	if fs, _ := disks.Get("avatars"); fs.(*localfilesystem.Local).Root != filepath.Join(v.RootPath, "avatars") {
+		t.Errorf("expected the disk to be relative to the root path, got %v", fs)
+	}
+	if local, ok := v.FileSystems["LOCAL"].(localfilesystem.Local); !ok || local.Root != filepath.Join(v.RootPath, "storage") {
+		t.Errorf("expected the local filesystem in FileSystems, got %v", v.FileSystems)
+	}
+
+	t.Setenv("DISK_LOCAL_DRIVER", "memory")
+	t.Setenv("DISKS", "scratch,local")
+	t.Setenv("DEFAULT_DISK", "local")
+	disks, err = v.createDisks()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fs, _ := disks.Get(""); reflect.TypeOf(fs) != reflect.TypeOf(&memfilesystem.Mem{}) {
+		t.Errorf("expected the local disk to replace LOCAL_ROOT and be the default, got %T", fs)
+	}
+
+	t.Setenv("DEFAULT_DISK", "missing")
+	if _, err := v.createDisks(); !errors.Is(err, filesystems.ErrUnknownDisk) {
+		t.Errorf("expected ErrUnknownDisk for DEFAULT_DISK, got %v", err)
+	}
+
+	t.Setenv("DEFAULT_DISK", "")
+	t.Setenv("DISK_SCRATCH_DRIVER", "missing")
+	if _, err := v.createDisks(); !errors.Is(err, filesystems.ErrUnknownDriver) {
+		t.Errorf("expected ErrUnknownDriver, got %v", err)
+	}
+}
+
+func TestDisk(t *testing.T) {
+	v := newTestVelox()
+	if _, err := v.Disk(""); !errors.Is(err, filesystems.ErrUnknownDisk) {
+		t.Errorf("expected ErrUnknownDisk without disks, got %v", err)
+	}
+
+	fs := &memfilesystem.Mem{}
+	v.Disks = &filesystems.Disks{}
+	v.Disks.Add("scratch", fs)
+
+	if disk, err := v.Disk(""); err != nil || disk != fs {
+		t.Errorf("expected the default disk, got %v %v", disk, err)
+	}
+	if _, err := v.Disk("missing"); !errors.Is(err, filesystems.ErrUnknownDisk) {
+		t.Errorf("expected ErrUnknownDisk, got %v", err)
+	}
+}
@@ -0,0 +1,214 @@
+package velox
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strings"
+
+	"github.com/FernandoJVideira/velox/i18n"
+	"github.com/FernandoJVideira/velox/render"
+	"github.com/justinas/nosurf"
+)
+
+// HandleError is the central place where error responses are written. API requests (paths under
+// /api/ or clients asking for JSON) get JSON problem details; everyone else gets the page in
+// views/errors/<status> (or views/errors/error) when the application has one, and plain text
+// otherwise. In debug mode, server errors show a page with the error and a stack trace instead
+func (v *Velox) HandleError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if err != nil && status >= http.StatusInternalServerError {
+		v.ErrorLog.Println(err)
+	}
+
+	if v.isAPIRequest(r) {
+		v.writeProblemStatus(w, status, err)
+		return
+	}
+
+	if v.Debug && status >= http.StatusInternalServerError {
+		v.writeDebugPage(w, r, status, err, debug.Stack())
+		return
+	}
+
+	v.writeErrorPage(w, r, status)
+}
+
+// Recoverer recovers from panics in handlers, logs them, and answers with a 500 error through HandleError
+func (v *Velox) Recoverer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			rvr := recover()
+			if rvr == nil {
+				return
+			}
+			if rvr == http.ErrAbortHandler {
+				// the client went away; let net/http deal with it
+				panic(rvr)
+			}
+
+			stack := debug.Stack()
+			err, ok := rvr.(error)
+			if !ok {
+				err = fmt.Errorf("%v", rvr)
+			}
+			v.ErrorLog.Printf("panic: %v\n%s", err, stack)
+
+			if r.Header.Get("Connection") == "Upgrade" {
+				return
+			}
+
+			switch {
+			case v.isAPIRequest(r):
+				v.writeProblemStatus(w, http.StatusInternalServerError, err)
+			case v.Debug:
+				v.writeDebugPage(w, r, http.StatusInternalServerError, err, stack)
+			default:
+				v.writeErrorPage(w, r, http.StatusInternalServerError)
+			}
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isAPIRequest reports whether the request should be answered with JSON rather than a page
+func (v *Velox) isAPIRequest(r *http.Request) bool {
+	if r == nil {
+		return false
+	}
+	if strings.HasPrefix(r.URL.Path, "/api/") {
+		return true
+	}
+
+	accept := r.Header.Get("Accept")
+	return accept != "" && negotiate(accept, []string{"text/html", "application/json", "application/problem+json"}) != "text/html"
+}
+
+// writeProblemStatus writes a problem details document for status
+func (v *Velox) writeProblemStatus(w http.ResponseWriter, status int, err error) {
+	problem := &Problem{}
+	if v.Debug && err != nil {
+		problem.Detail = err.Error()
+	}
+	_ = v.WriteProblem(w, status, problem)
+}
+
+// writeErrorPage renders the application's error view for status, falling back to plain text
+func (v *Velox) writeErrorPage(w http.ResponseWriter, r *http.Request, status int) {
+	view := v.errorView(status)
+	if view != "" && v.Render != nil {
+		td := &render.TemplateData{
+			Data: map[string]interface{}{
+				"status":     status,
+				"statusText": http.StatusText(status),
+			},
+		}
+		// rendered without the request, so the flash message and form errors the user is about to
+		// see on the next page aren't taken from the session
+		if r != nil {
+			td.Locale = i18n.LocaleFromContext(r.Context())
+			td.CSRFToken = nosurf.Token(r)
+			td.IsAuthenticated = v.Session != nil && v.Session.Exists(r.Context(), "userID")
+		}
+
+		out, err := v.Render.Bytes(nil, view, nil, td)
+		if err == nil {
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.WriteHeader(status)
+			_, _ = w.Write(out)
+			return
+		}
+		v.ErrorLog.Println(err)
+	}
+
+	http.Error(w, http.StatusText(status), status)
+}
+
+// errorView returns the view to render for status, or an empty string when the application has none
+func (v *Velox) errorView(status int) string {
+	ext := ".jet"
+	if strings.ToLower(v.config.renderer) == "go" {
+		ext = ".page.tmpl"
+	}
+
+	for _, view := range []string{fmt.Sprintf("errors/%d", status), "errors/error"} {
+		if _, err := os.Stat(fmt.Sprintf("%s/views/%s%s", v.RootPath, view, ext)); err == nil {
+			return view
+		}
+	}
+	return ""
+}
+
+var debugPage = template.Must(template.New("debug").Parse(`<!doctype html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Status}} {{.StatusText}}</title>
+<style>
+body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", sans-serif; margin: 0; color: #222; }
+header { background: #b91c1c; color: #fff; padding: 24px 32px; }
+header h1 { margin: 0 0 8px; font-size: 22px; }
+header p { margin: 0; font-family: monospace; font-size: 15px; white-space: pre-wrap; }
+section { padding: 16px 32px; }
+h2 { font-size: 16px; }
+pre { background: #f4f4f5; padding: 16px; overflow-x: auto; font-size: 13px; line-height: 1.5; }
+table { border-collapse: collapse; font-size: 13px; }
+td { padding: 2px 16px 2px 0; font-family: monospace; vertical-align: top; }
+</style>
+</head>
+<body>
+<header>
+<h1>{{.Status}} {{.StatusText}}</h1>
+<p>{{.Error}}</p>
+</header>
+<section>
+<h2>Request</h2>
+<table>
+<tr><td>{{.Method}}</td><td>{{.URL}}</td></tr>
+{{range $name, $values := .Headers}}<tr><td>{{$name}}</td><td>{{range $values}}{{.}} {{end}}</td></tr>
+{{end}}</table>
+<h2>Stack trace</h2>
+<pre>{{.Stack}}</pre>
+</section>
+</body>
+</html>
+`))
+
+// writeDebugPage shows the details of a server error; it is only used in debug mode
+func (v *Velox) writeDebugPage(w http.ResponseWriter, r *http.Request, status int, err error, stack []byte) {
+	if err == nil {
+		err = errors.New(http.StatusText(status))
+	}
+
+	data := struct {
+		Status     int
+		StatusText string
+		Error      string
+		Method     string
+		URL        string
+		Headers    http.Header
+		Stack      string
+	}{
+		Status:     status,
+		StatusText: http.StatusText(status),
+		Error:      err.Error(),
+		Stack:      string(stack),
+	}
+	if r != nil {
+		data.Method = r.Method
+		data.URL = r.URL.String()
+		data.Headers = r.Header.Clone()
+		for _, secret := range []string{"Authorization", "Cookie"} {
+			if data.Headers.Get(secret) != "" {
+				data.Headers.Set(secret, "[hidden]")
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_ = debugPage.Execute(w, data)
+}
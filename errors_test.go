@@ -0,0 +1,53 @@
+package velox
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FernandoJVideira/velox/i18n"
+	"github.com/alexedwards/scs/v2"
+)
+
+func TestHandleError_ErrorPage(t *testing.T) {
+	v := newTestVelox()
+	v.Session = scs.New()
+	v.Render.Session = v.Session
+
+	ctx, err := v.Session.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx = i18n.WithLocale(ctx, "pt")
+	v.Session.Put(ctx, "flash", "saved")
+	v.Session.Put(ctx, "errors", map[string]string{"email": "required"})
+
+	r := httptest.NewRequest("GET", "/missing", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	v.HandleError(w, r, http.StatusNotFound, nil)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected status 404, got %d", w.Code)
+	}
+	if w.Body.String() != "404 Not Found||pt" {
+		t.Errorf("unexpected error page %q", w.Body.String())
+	}
+
+	// the next page still gets what was flashed
+	if v.Session.GetString(ctx, "flash") != "saved" || !v.Session.Exists(ctx, "errors") {
+		t.Error("expected the error page to leave the session data alone")
+	}
+}
+
+func TestHandleError_PlainText(t *testing.T) {
+	v := newTestVelox()
+
+	r := httptest.NewRequest("GET", "/", nil)
+	w := httptest.NewRecorder()
+	v.HandleError(w, r, http.StatusTeapot, nil)
+
+	if w.Code != http.StatusTeapot || w.Body.String() != "I'm a teapot\n" {
+		t.Errorf("expected a plain text page without an error view, got %d %q", w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,146 @@
+package filesystems
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrUnknownDriver = errors.New("filesystems: unknown driver")
+	ErrUnknownDisk   = errors.New("filesystems: unknown disk")
+)
+
+// DiskConfig describes a disk: a filesystem with a name, such as avatars, made by a driver
+type DiskConfig struct {
+	Name   string
+	Driver string
+	// Options are the settings of the disk by lower case name, e.g. bucket
+	Options map[string]string
+	// RootPath is the root folder of the application, which relative paths are relative to
+	RootPath string
+}
+
+// Factory makes the filesystem of a disk
+type Factory func(config DiskConfig) (FS, error)
+
+var (
+	driversMu sync.RWMutex
+	drivers   = make(map[string]Factory)
+)
+
+// Register makes a driver available to disks, usually from the init function of the package
+// implementing it. It panics when the driver is already registered
+func Register(driver string, factory Factory) {
+	driversMu.Lock()
+	defer driversMu.Unlock()
+
+	if factory == nil {
+		panic("filesystems: Register factory is nil")
+	}
+	if _, ok := drivers[driver]; ok {
+		panic("filesystems: Register called twice for driver " + driver)
+	}
+	drivers[driver] = factory
+}
+
+// Drivers returns the names of the registered drivers, sorted
+func Drivers() []string {
+	driversMu.RLock()
+	defer driversMu.RUnlock()
+
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open makes the filesystem of a disk with its driver
+func Open(config DiskConfig) (FS, error) {
+	driversMu.RLock()
+	factory, ok := drivers[config.Driver]
+	driversMu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("%w %q for disk %s", ErrUnknownDriver, config.Driver, config.Name)
+	}
+
+	fs, err := factory(config)
+	if err != nil {
+		return nil, fmt.Errorf("filesystems: disk %s: %w", config.Name, err)
+	}
+	return fs, nil
+}
+
+// Disks holds filesystems by name, one of which is the default. It is safe for concurrent use
+type Disks struct {
+	mu          sync.RWMutex
+	disks       map[string]FS
+	defaultDisk string
+}
+
+// Add adds a disk, replacing any disk with the same name. The first disk added is the default
+// until another one is chosen
+func (d *Disks) Add(name string, fs FS) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.disks == nil {
+		d.disks = make(map[string]FS)
+	}
+	d.disks[name] = fs
+	if d.defaultDisk == "" {
+		d.defaultDisk = name
+	}
+}
+
+// SetDefault chooses the disk returned for an empty name
+func (d *Disks) SetDefault(name string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, ok := d.disks[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrUnknownDisk, name)
+	}
+	d.defaultDisk = name
+	return nil
+}
+
+// Default returns the name of the default disk, or an empty string when there are no disks
+func (d *Disks) Default() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	return d.defaultDisk
+}
+
+// Get returns the disk called name, or the default disk when name is empty
+func (d *Disks) Get(name string) (FS, error) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	if name == "" {
+		name = d.defaultDisk
+	}
+	fs, ok := d.disks[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownDisk, name)
+	}
+	return fs, nil
+}
+
+// Names returns the names of the disks, sorted
+func (d *Disks) Names() []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	names := make([]string, 0, len(d.disks))
+	for name := range d.disks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
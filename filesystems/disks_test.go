@@ -0,0 +1,91 @@
+package filesystems_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+	"github.com/FernandoJVideira/velox/filesystems/memfilesystem"
+)
+
+func TestOpen(t *testing.T) {
+	fs, err := filesystems.Open(filesystems.DiskConfig{Name: "test", Driver: "memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fs.(*memfilesystem.Mem); !ok {
+		t.Errorf("expected a memory filesystem, got %T", fs)
+	}
+
+	_, err = filesystems.Open(filesystems.DiskConfig{Name: "test", Driver: "missing"})
+	if !errors.Is(err, filesystems.ErrUnknownDriver) {
+		t.Errorf("expected ErrUnknownDriver, got %v", err)
+	}
+}
+
+func TestRegister(t *testing.T) {
+	filesystems.Register("failing", func(config filesystems.DiskConfig) (filesystems.FS, error) {
+		return nil, errors.New("bucket is not set")
+	})
+
+	if !strings.Contains(strings.Join(filesystems.Drivers(), ","), "failing") {
+		t.Errorf("expected the driver to be registered, got %v", filesystems.Drivers())
+	}
+
+	_, err := filesystems.Open(filesystems.DiskConfig{Name: "avatars", Driver: "failing"})
+	if err == nil || !strings.Contains(err.Error(), "disk avatars: bucket is not set") {
+		t.Errorf("expected the error of the factory, got %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("expected registering a driver twice to panic")
+		}
+	}()
+	filesystems.Register("memory", func(config filesystems.DiskConfig) (filesystems.FS, error) {
+		return &memfilesystem.Mem{}, nil
+	})
+}
+
+func TestDisks(t *testing.T) {
+	var disks filesystems.Disks
+
+	if _, err := disks.Get(""); !errors.Is(err, filesystems.ErrUnknownDisk) {
+		t.Errorf("expected ErrUnknownDisk without disks, got %v", err)
+	}
+
+	avatars, backups := &memfilesystem.Mem{}, &memfilesystem.Mem{}
+	disks.Add("avatars", avatars)
+	disks.Add("backups", backups)
+
+	tests := []struct {
+		name     string
+		expected filesystems.FS
+	}{
+		{"", avatars},
+		{"avatars", avatars},
+		{"backups", backups},
+	}
+
+	for _, e := range tests {
+		fs, err := disks.Get(e.name)
+		if err != nil || fs != e.expected {
+			t.Errorf("%q: unexpected %v, %v", e.name, fs, err)
+		}
+	}
+
+	if err := disks.SetDefault("backups"); err != nil {
+		t.Fatal(err)
+	}
+	if fs, _ := disks.Get(""); fs != backups || disks.Default() != "backups" {
+		t.Error("expected backups to be the default")
+	}
+	if err := disks.SetDefault("missing"); !errors.Is(err, filesystems.ErrUnknownDisk) {
+		t.Errorf("expected ErrUnknownDisk, got %v", err)
+	}
+
+	if strings.Join(disks.Names(), ",") != "avatars,backups" {
+		t.Errorf("unexpected names %v", disks.Names())
+	}
+}
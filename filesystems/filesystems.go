@@ -1,14 +1,53 @@
 package filesystems
 
-import "time"
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"io"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
 
 // FS is the interface that wraps the basic methods for a filesystem
 // In order to satisfy this interface, a filesystem must implement the following methods:
 type FS interface {
-	Put(filename, folder string) error
+	// Put stores the local file fileName in folder, as described by the options if there are any
+	Put(fileName, folder string, opts ...PutOptions) error
 	Get(destination string, items ...string) error
 	List(prefix string) ([]Listing, error)
+	// Delete reports whether every file was removed; DeleteWithErrors says why they weren't
 	Delete(itemsToDelete []string) bool
+
+	// PutStream stores the file read from r at key, replacing any file there
+	PutStream(ctx context.Context, key string, r io.Reader, opts PutOptions) error
+	// Open returns the content of the file at key, which has to be closed. When there is no such
+	// file, the error wraps fs.ErrNotExist, as it does for Stat
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Stat describes the file at key
+	Stat(ctx context.Context, key string) (FileInfo, error)
+	// Exists reports whether there is a file at key
+	Exists(ctx context.Context, key string) (bool, error)
+	// Copy copies the file at src to dst, replacing any file there
+	Copy(ctx context.Context, src, dst string) error
+	// Move moves the file at src to dst, replacing any file there
+	Move(ctx context.Context, src, dst string) error
+	// DeleteWithErrors removes files, and returns why the ones that couldn't be removed weren't.
+	// Files that don't exist are ignored
+	DeleteWithErrors(ctx context.Context, keys []string) error
+}
+
+// Presigner is implemented by filesystems that give out temporary links to their files, such as
+// S3, so browsers download and upload files without going through the application
+type Presigner interface {
+	// TemporaryURL returns a link to download the file at key, which works for expiry
+	TemporaryURL(key string, expiry time.Duration) (string, error)
+	// TemporaryUploadURL returns a link to upload a file to key with a PUT request, which works for
+	// expiry. When contentType is set, the request has to send it as its Content-Type
+	TemporaryUploadURL(key string, expiry time.Duration, contentType string) (string, error)
 }
 
 // Listing is a struct that contains the information of a file or folder
@@ -19,3 +58,89 @@ type Listing struct {
 	Size         float64
 	IsDir        bool
 }
+
+// FileInfo describes a stored file
+type FileInfo struct {
+	Key string
+	// Size is the size of the file in bytes
+	Size         int64
+	LastModified time.Time
+	// ContentType is empty when the filesystem doesn't keep it
+	ContentType string
+	Etag        string
+}
+
+// Visibility says who can read a stored file
+type Visibility string
+
+const (
+	// Private files are only readable with the filesystem's credentials, or through temporary links
+	Private Visibility = "private"
+	// Public files are readable by anyone, e.g. through the bucket's URL on S3
+	Public Visibility = "public"
+)
+
+// FileMode returns the permissions of files with the visibility, on filesystems that have them
+func (v Visibility) FileMode() os.FileMode {
+	if v == Public {
+		return 0644
+	}
+	return 0600
+}
+
+// VisibilityOf returns the visibility of a file with the permissions mode
+func VisibilityOf(mode os.FileMode) Visibility {
+	if mode.Perm()&0004 != 0 {
+		return Public
+	}
+	return Private
+}
+
+// PutOptions describes a file being stored. Drivers keep what they can: S3, Minio and the memory
+// filesystem keep every option, WebDAV sends the headers along, and local disk and SFTP only apply
+// the visibility, as the permissions of the file
+type PutOptions struct {
+	// Visibility defaults to Private
+	Visibility  Visibility
+	ContentType string
+	// CacheControl is the Cache-Control header the file is served with, e.g. max-age=3600
+	CacheControl string
+	// ContentDisposition is the Content-Disposition header the file is served with, e.g.
+	// attachment; filename="report.pdf"
+	ContentDisposition string
+	// Metadata is kept with the file, e.g. as x-amz-meta- headers on S3
+	Metadata map[string]string
+	// Size is the size of the file in bytes; 0 or less means it is not known in advance
+	Size int64
+}
+
+// FileOptions returns the options to store the local file f with Put: the first of opts, if any,
+// with its size, and its content type guessed from its extension unless it is set
+func FileOptions(f *os.File, opts ...PutOptions) (PutOptions, error) {
+	var options PutOptions
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return options, err
+	}
+	options.Size = info.Size()
+
+	if options.ContentType == "" {
+		options.ContentType = mime.TypeByExtension(filepath.Ext(f.Name()))
+	}
+	return options, nil
+}
+
+// TempKey returns a hidden key next to key, for filesystems that write a file under it and rename
+// it to key once all of it is written, so a failed write never leaves a truncated file at key
+func TempKey(key string) (string, error) {
+	b := make([]byte, 8)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(path.Dir(key), ".upload-"+hex.EncodeToString(b)), nil
+}
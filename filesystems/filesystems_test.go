@@ -0,0 +1,82 @@
+package filesystems_test
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+)
+
+func TestFileOptions(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "photo.png")
+	if err := os.WriteFile(name, []byte("not really a png"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	tests := []struct {
+		name        string
+		opts        []filesystems.PutOptions
+		contentType string
+		visibility  filesystems.Visibility
+	}{
+		{"no options", nil, "image/png", ""},
+		{"options", []filesystems.PutOptions{{Visibility: filesystems.Public, CacheControl: "no-cache"}}, "image/png", filesystems.Public},
+		{"content type", []filesystems.PutOptions{{ContentType: "application/octet-stream"}}, "application/octet-stream", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options, err := filesystems.FileOptions(f, tt.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if options.Size != 16 || options.ContentType != tt.contentType || options.Visibility != tt.visibility {
+				t.Errorf("unexpected options %+v", options)
+			}
+		})
+	}
+}
+
+func TestVisibility(t *testing.T) {
+	tests := []struct {
+		visibility filesystems.Visibility
+		mode       os.FileMode
+	}{
+		{"", 0600},
+		{filesystems.Private, 0600},
+		{filesystems.Public, 0644},
+	}
+
+	for _, tt := range tests {
+		if mode := tt.visibility.FileMode(); mode != tt.mode {
+			t.Errorf("%q: expected mode %v, got %v", tt.visibility, tt.mode, mode)
+		}
+	}
+
+	if filesystems.VisibilityOf(0640) != filesystems.Private || filesystems.VisibilityOf(0755) != filesystems.Public {
+		t.Error("expected files readable by everyone to be public")
+	}
+}
+
+func TestTempKey(t *testing.T) {
+	for _, key := range []string{"videos/clip.mp4", "clip.mp4"} {
+		tmp, err := filesystems.TempKey(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if path.Dir(tmp) != path.Dir(key) || !strings.HasPrefix(path.Base(tmp), ".upload-") {
+			t.Errorf("%s: expected a hidden key in the same folder, got %s", key, tmp)
+		}
+		if other, _ := filesystems.TempKey(key); other == tmp {
+			t.Errorf("%s: expected a different key every time, got %s twice", key, tmp)
+		}
+	}
+}
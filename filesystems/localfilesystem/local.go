@@ -0,0 +1,311 @@
+package localfilesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+)
+
+// ErrInvalidKey is returned for keys that point outside of the root folder
+var ErrInvalidKey = errors.New("localfilesystem: key is outside of the root folder")
+
+func init() {
+	filesystems.Register("local", func(config filesystems.DiskConfig) (filesystems.FS, error) {
+		root := config.Options["root"]
+		if root == "" {
+			root = "storage"
+		}
+		if !filepath.IsAbs(root) {
+			root = filepath.Join(config.RootPath, root)
+		}
+		return &Local{Root: root}, nil
+	})
+}
+
+// Local stores files in a folder on local disk. Keys are slash separated paths relative to Root,
+// and can't point outside of it
+type Local struct {
+	Root string
+}
+
+func (l *Local) Put(fileName, folder string, opts ...filesystems.PutOptions) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	options, err := filesystems.FileOptions(f, opts...)
+	if err != nil {
+		return err
+	}
+	return l.PutStream(context.Background(), path.Join(folder, filepath.Base(fileName)), f, options)
+}
+
+// PutStream writes the file read from r at key, readable by everyone when it is public and only by
+// its owner otherwise. The file is written to a temporary file next to it and renamed once
+// complete, so a failed write never leaves a partial file behind
+func (l *Local) PutStream(ctx context.Context, key string, r io.Reader, opts filesystems.PutOptions) error {
+	file, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(file)
+	err = os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, &contextReader{ctx: ctx, r: r})
+	if err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), opts.Visibility.FileMode())
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+func (l *Local) Get(destination string, items ...string) error {
+	for _, item := range items {
+		err := func() error {
+			file, err := l.path(item)
+			if err != nil {
+				return err
+			}
+
+			srcFile, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer srcFile.Close()
+
+			dstFile, err := os.Create(filepath.Join(destination, path.Base(item)))
+			if err != nil {
+				return err
+			}
+			defer dstFile.Close()
+
+			_, err = io.Copy(dstFile, srcFile)
+			if err != nil {
+				return err
+			}
+			return dstFile.Sync()
+		}()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the files under the folder prefix, and under its subfolders, with their keys.
+// Files and folders starting with a dot are left out
+func (l *Local) List(prefix string) ([]filesystems.Listing, error) {
+	var listing []filesystems.Listing
+
+	dir := filepath.Clean(l.Root)
+	if strings.Trim(prefix, "/") != "" {
+		var err error
+		dir, err = l.path(prefix)
+		if err != nil {
+			return listing, err
+		}
+	}
+
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if file == dir {
+			return nil
+		}
+		if strings.HasPrefix(entry.Name(), ".") {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		key, err := filepath.Rel(l.Root, file)
+		if err != nil {
+			return err
+		}
+
+		b := float64(info.Size())
+		kb := b / 1024
+		mb := kb / 1024
+		listing = append(listing, filesystems.Listing{
+			LastModified: info.ModTime(),
+			Key:          filepath.ToSlash(key),
+			Size:         mb,
+		})
+		return nil
+	})
+	if os.IsNotExist(err) {
+		return listing, nil
+	}
+
+	return listing, err
+}
+
+// Delete removes files; files that don't exist are ignored
+func (l *Local) Delete(itemsToDelete []string) bool {
+	for _, item := range itemsToDelete {
+		file, err := l.path(item)
+		if err != nil {
+			return false
+		}
+		err = os.Remove(file)
+		if err != nil && !os.IsNotExist(err) {
+			return false
+		}
+	}
+	return true
+}
+
+func (l *Local) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(file)
+}
+
+func (l *Local) Stat(ctx context.Context, key string) (filesystems.FileInfo, error) {
+	file, err := l.path(key)
+	if err != nil {
+		return filesystems.FileInfo{}, err
+	}
+
+	info, err := os.Stat(file)
+	if err != nil {
+		return filesystems.FileInfo{}, err
+	}
+	if info.IsDir() {
+		return filesystems.FileInfo{}, &fs.PathError{Op: "stat", Path: key, Err: fs.ErrNotExist}
+	}
+
+	return filesystems.FileInfo{
+		Key:          strings.TrimLeft(filepath.ToSlash(key), "/"),
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+		ContentType:  mime.TypeByExtension(filepath.Ext(file)),
+	}, nil
+}
+
+func (l *Local) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := l.Stat(ctx, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Copy copies the file at src to dst, with the same visibility
+func (l *Local) Copy(ctx context.Context, src, dst string) error {
+	from, err := l.path(src)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.Open(from)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	return l.PutStream(ctx, dst, file, filesystems.PutOptions{
+		Visibility: filesystems.VisibilityOf(info.Mode()),
+		Size:       info.Size(),
+	})
+}
+
+func (l *Local) Move(ctx context.Context, src, dst string) error {
+	from, err := l.path(src)
+	if err != nil {
+		return err
+	}
+	to, err := l.path(dst)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(to), 0755)
+	if err != nil {
+		return err
+	}
+	return os.Rename(from, to)
+}
+
+func (l *Local) DeleteWithErrors(ctx context.Context, keys []string) error {
+	var errs []error
+	for _, key := range keys {
+		file, err := l.path(key)
+		if err == nil {
+			err = os.Remove(file)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// path returns the path of key on disk, and fails when key points outside of the root folder, e.g.
+// with ../, or at the root folder itself. A leading slash is allowed, and refers to the root folder
+func (l *Local) path(key string) (string, error) {
+	key = strings.TrimLeft(filepath.ToSlash(key), "/")
+	if key == "" || strings.Contains(key, "\x00") || !filepath.IsLocal(filepath.FromSlash(key)) || filepath.Clean(key) == "." {
+		return "", ErrInvalidKey
+	}
+	return filepath.Join(l.Root, filepath.FromSlash(key)), nil
+}
+
+// contextReader stops reading once its context is done
+type contextReader struct {
+	ctx context.Context
+	r   io.Reader
+}
+
+func (c *contextReader) Read(p []byte) (int, error) {
+	if err := c.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return c.r.Read(p)
+}
@@ -0,0 +1,253 @@
+package localfilesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+)
+
+func newTestLocal(t *testing.T) *Local {
+	root, err := os.MkdirTemp(testDir, "root-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Local{Root: root}
+}
+
+func TestLocal_PutGet(t *testing.T) {
+	l := newTestLocal(t)
+
+	src := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(src, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := l.Put(src, "docs/2024"); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(filepath.Join(l.Root, "docs", "2024", "hello.txt"))
+	if err != nil || string(content) != "hello" {
+		t.Fatalf("expected the file to be stored under the root, got %q, %v", content, err)
+	}
+
+	dst := t.TempDir()
+	if err := l.Get(dst, "docs/2024/hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+	content, _ = os.ReadFile(filepath.Join(dst, "hello.txt"))
+	if string(content) != "hello" {
+		t.Errorf("expected hello, got %q", content)
+	}
+
+	if err := l.Get(dst, "docs/missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestLocal_PutStream(t *testing.T) {
+	l := newTestLocal(t)
+
+	err := l.PutStream(context.Background(), "/a/b.txt", strings.NewReader("streamed"), filesystems.PutOptions{Size: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(filepath.Join(l.Root, "a", "b.txt"))
+	if string(content) != "streamed" {
+		t.Errorf("expected streamed, got %q", content)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = l.PutStream(ctx, "a/c.txt", strings.NewReader("cancelled"), filesystems.PutOptions{Size: -1})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the write to be cancelled, got %v", err)
+	}
+
+	entries, _ := os.ReadDir(filepath.Join(l.Root, "a"))
+	if len(entries) != 1 {
+		t.Errorf("expected no partial file to be left, got %d files", len(entries))
+	}
+}
+
+func TestLocal_Visibility(t *testing.T) {
+	l := newTestLocal(t)
+	ctx := context.Background()
+
+	tests := []struct {
+		name       string
+		visibility filesystems.Visibility
+		mode       os.FileMode
+	}{
+		{"default", "", 0600},
+		{"private", filesystems.Private, 0600},
+		{"public", filesystems.Public, 0644},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := tt.name + ".txt"
+			err := l.PutStream(ctx, key, strings.NewReader(tt.name), filesystems.PutOptions{Visibility: tt.visibility, Size: -1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(filepath.Join(l.Root, key))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Mode().Perm() != tt.mode {
+				t.Errorf("expected mode %v, got %v", tt.mode, info.Mode().Perm())
+			}
+
+			// copies keep the visibility of the original
+			if err := l.Copy(ctx, key, "copies/"+key); err != nil {
+				t.Fatal(err)
+			}
+			info, _ = os.Stat(filepath.Join(l.Root, "copies", key))
+			if info.Mode().Perm() != tt.mode {
+				t.Errorf("expected the copy to have mode %v, got %v", tt.mode, info.Mode().Perm())
+			}
+		})
+	}
+}
+
+func TestLocal_ListDelete(t *testing.T) {
+	l := newTestLocal(t)
+
+	for _, key := range []string{"a.txt", "images/b.png", "images/thumbs/c.png", ".hidden", "images/.tmp/d"} {
+		if err := l.PutStream(context.Background(), key, strings.NewReader(key), filesystems.PutOptions{Size: -1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		prefix string
+		keys   []string
+	}{
+		{"", []string{"a.txt", "images/b.png", "images/thumbs/c.png"}},
+		{"/", []string{"a.txt", "images/b.png", "images/thumbs/c.png"}},
+		{"images", []string{"images/b.png", "images/thumbs/c.png"}},
+		{"images/thumbs/", []string{"images/thumbs/c.png"}},
+		{"missing", nil},
+	}
+
+	for _, e := range tests {
+		listing, err := l.List(e.prefix)
+		if err != nil {
+			t.Errorf("%q: %s", e.prefix, err)
+			continue
+		}
+		var keys []string
+		for _, item := range listing {
+			keys = append(keys, item.Key)
+		}
+		sort.Strings(keys)
+		if strings.Join(keys, ",") != strings.Join(e.keys, ",") {
+			t.Errorf("%q: expected %v, got %v", e.prefix, e.keys, keys)
+		}
+	}
+
+	if !l.Delete([]string{"a.txt", "images/b.png", "missing.txt"}) {
+		t.Error("expected the files to be deleted")
+	}
+	listing, _ := l.List("")
+	if len(listing) != 1 || listing[0].Key != "images/thumbs/c.png" {
+		t.Errorf("expected one file to be left, got %v", listing)
+	}
+}
+
+func TestLocal_Streams(t *testing.T) {
+	l := newTestLocal(t)
+	ctx := context.Background()
+
+	err := l.PutStream(ctx, "a.txt", strings.NewReader("hello"), filesystems.PutOptions{Size: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := l.Open(ctx, "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(reader)
+	_ = reader.Close()
+	if string(content) != "hello" {
+		t.Errorf("expected hello, got %q", content)
+	}
+
+	info, err := l.Stat(ctx, "/a.txt")
+	if err != nil || info.Key != "a.txt" || info.Size != 5 || !strings.HasPrefix(info.ContentType, "text/plain") {
+		t.Errorf("unexpected info %+v, %v", info, err)
+	}
+
+	if err := l.Copy(ctx, "a.txt", "b/copy.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := l.Move(ctx, "a.txt", "c/moved.txt"); err != nil {
+		t.Fatal(err)
+	}
+
+	for key, expected := range map[string]bool{"a.txt": false, "b/copy.txt": true, "c/moved.txt": true, "b": false} {
+		exists, err := l.Exists(ctx, key)
+		if err != nil || exists != expected {
+			t.Errorf("%s: expected %v, got %v, %v", key, expected, exists, err)
+		}
+	}
+
+	if _, err := l.Open(ctx, "a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := l.Stat(ctx, "b"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected folders not to be files, got %v", err)
+	}
+
+	err = l.DeleteWithErrors(ctx, []string{"b/copy.txt", "c/moved.txt", "missing", "../outside"})
+	if !errors.Is(err, ErrInvalidKey) {
+		t.Errorf("expected the invalid key to be reported, got %v", err)
+	}
+	if listing, _ := l.List(""); len(listing) != 0 {
+		t.Errorf("expected every file to be deleted, got %v", listing)
+	}
+}
+
+func TestLocal_PathTraversal(t *testing.T) {
+	l := newTestLocal(t)
+
+	outside := filepath.Join(filepath.Dir(l.Root), "outside.txt")
+	if err := os.WriteFile(outside, []byte("secret"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := []string{"../outside.txt", "a/../../outside.txt", "/../outside.txt", "..", "a\x00b", "", "a/.."}
+	for _, key := range keys {
+		if err := l.PutStream(context.Background(), key, strings.NewReader("x"), filesystems.PutOptions{Size: -1}); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("put %q: expected ErrInvalidKey, got %v", key, err)
+		}
+		if err := l.Get(t.TempDir(), key); !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("get %q: expected ErrInvalidKey, got %v", key, err)
+		}
+		if _, err := l.List(key); key != "" && !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("list %q: expected ErrInvalidKey, got %v", key, err)
+		}
+		if l.Delete([]string{key}) {
+			t.Errorf("delete %q: expected a failure", key)
+		}
+	}
+
+	if content, _ := os.ReadFile(outside); string(content) != "secret" {
+		t.Error("expected the file outside of the root to be left alone")
+	}
+
+	// keys that stay inside the root are fine
+	if err := l.PutStream(context.Background(), "a/../b.txt", strings.NewReader("x"), filesystems.PutOptions{Size: -1}); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,22 @@
+package localfilesystem
+
+import (
+	"log"
+	"os"
+	"testing"
+)
+
+var testDir string
+
+func TestMain(m *testing.M) {
+	var err error
+	testDir, err = os.MkdirTemp("", "velox-local")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+
+	_ = os.RemoveAll(testDir)
+	os.Exit(code)
+}
@@ -0,0 +1,267 @@
+package memfilesystem
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+)
+
+func init() {
+	filesystems.Register("memory", func(config filesystems.DiskConfig) (filesystems.FS, error) {
+		return &Mem{}, nil
+	})
+}
+
+// Mem keeps files in memory, for tests. Its zero value is ready to use, and it is safe for
+// concurrent use. Keys are slash separated, without a leading slash
+type Mem struct {
+	mu    sync.RWMutex
+	files map[string]File
+}
+
+// File is a file stored on a Mem
+type File struct {
+	Data               []byte
+	Visibility         filesystems.Visibility
+	ContentType        string
+	CacheControl       string
+	ContentDisposition string
+	Metadata           map[string]string
+	LastModified       time.Time
+}
+
+func (m *Mem) Put(fileName, folder string, opts ...filesystems.PutOptions) error {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	options, err := filesystems.FileOptions(f, opts...)
+	if err != nil {
+		return err
+	}
+	return m.PutStream(context.Background(), path.Join(folder, filepath.Base(fileName)), f, options)
+}
+
+func (m *Mem) PutStream(ctx context.Context, key string, r io.Reader, opts filesystems.PutOptions) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	visibility := opts.Visibility
+	if visibility == "" {
+		visibility = filesystems.Private
+	}
+
+	m.set(key, File{
+		Data:               data,
+		Visibility:         visibility,
+		ContentType:        opts.ContentType,
+		CacheControl:       opts.CacheControl,
+		ContentDisposition: opts.ContentDisposition,
+		Metadata:           opts.Metadata,
+	})
+	return nil
+}
+
+func (m *Mem) Get(destination string, items ...string) error {
+	for _, item := range items {
+		file, ok := m.File(item)
+		if !ok {
+			return &fs.PathError{Op: "get", Path: item, Err: fs.ErrNotExist}
+		}
+
+		err := os.WriteFile(filepath.Join(destination, path.Base(item)), file.Data, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// List returns the files whose keys start with prefix, sorted by key. Files starting with a dot
+// are left out
+func (m *Mem) List(prefix string) ([]filesystems.Listing, error) {
+	var listing []filesystems.Listing
+	prefix = strings.TrimLeft(prefix, "/")
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for key, file := range m.files {
+		if !strings.HasPrefix(key, prefix) || strings.HasPrefix(path.Base(key), ".") {
+			continue
+		}
+
+		b := float64(len(file.Data))
+		kb := b / 1024
+		mb := kb / 1024
+		listing = append(listing, filesystems.Listing{
+			LastModified: file.LastModified,
+			Key:          key,
+			Size:         mb,
+		})
+	}
+
+	sort.Slice(listing, func(i, j int) bool {
+		return listing[i].Key < listing[j].Key
+	})
+	return listing, nil
+}
+
+// Delete removes files; files that don't exist are ignored
+func (m *Mem) Delete(itemsToDelete []string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, item := range itemsToDelete {
+		delete(m.files, cleanKey(item))
+	}
+	return true
+}
+
+func (m *Mem) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	file, ok := m.File(key)
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: key, Err: fs.ErrNotExist}
+	}
+	return io.NopCloser(bytes.NewReader(file.Data)), nil
+}
+
+func (m *Mem) Stat(ctx context.Context, key string) (filesystems.FileInfo, error) {
+	file, ok := m.File(key)
+	if !ok {
+		return filesystems.FileInfo{}, &fs.PathError{Op: "stat", Path: key, Err: fs.ErrNotExist}
+	}
+
+	return filesystems.FileInfo{
+		Key:          cleanKey(key),
+		Size:         int64(len(file.Data)),
+		LastModified: file.LastModified,
+		ContentType:  file.ContentType,
+	}, nil
+}
+
+func (m *Mem) Exists(ctx context.Context, key string) (bool, error) {
+	return m.Has(key), nil
+}
+
+func (m *Mem) Copy(ctx context.Context, src, dst string) error {
+	file, ok := m.File(src)
+	if !ok {
+		return &fs.PathError{Op: "copy", Path: src, Err: fs.ErrNotExist}
+	}
+
+	m.set(dst, file)
+	return nil
+}
+
+func (m *Mem) Move(ctx context.Context, src, dst string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	file, ok := m.files[cleanKey(src)]
+	if !ok {
+		return &fs.PathError{Op: "move", Path: src, Err: fs.ErrNotExist}
+	}
+	delete(m.files, cleanKey(src))
+	m.files[cleanKey(dst)] = file
+	return nil
+}
+
+func (m *Mem) DeleteWithErrors(ctx context.Context, keys []string) error {
+	m.Delete(keys)
+	return nil
+}
+
+// File returns the file stored at key
+func (m *Mem) File(key string) (File, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	file, ok := m.files[cleanKey(key)]
+	file.Data = append([]byte(nil), file.Data...)
+	file.Metadata = maps.Clone(file.Metadata)
+	return file, ok
+}
+
+// Content returns the content of the file stored at key, or nil when there is none
+func (m *Mem) Content(key string) []byte {
+	file, _ := m.File(key)
+	return file.Data
+}
+
+// Has reports whether a file is stored at key
+func (m *Mem) Has(key string) bool {
+	_, ok := m.File(key)
+	return ok
+}
+
+// Keys returns the keys of every file, sorted
+func (m *Mem) Keys() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]string, 0, len(m.files))
+	for key := range m.files {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Len returns how many files are stored
+func (m *Mem) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return len(m.files)
+}
+
+// Set stores data at key as a private file, e.g. to set up a test
+func (m *Mem) Set(key string, data []byte) {
+	m.set(key, File{Data: data, Visibility: filesystems.Private})
+}
+
+// Reset removes every file
+func (m *Mem) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.files = nil
+}
+
+func (m *Mem) set(key string, file File) {
+	file.Data = append([]byte{}, file.Data...)
+	file.Metadata = maps.Clone(file.Metadata)
+	file.LastModified = time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.files == nil {
+		m.files = make(map[string]File)
+	}
+	m.files[cleanKey(key)] = file
+}
+
+// cleanKey drops the leading slash other drivers' keys may have, e.g. when Put is given an empty folder
+func cleanKey(key string) string {
+	return strings.TrimLeft(path.Clean("/"+key), "/")
+}
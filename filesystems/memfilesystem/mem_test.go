@@ -0,0 +1,226 @@
+package memfilesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+)
+
+// Mem must be usable wherever a filesystem is
+var _ filesystems.FS = &Mem{}
+
+func TestMem_PutGet(t *testing.T) {
+	m := &Mem{}
+
+	src := filepath.Join(t.TempDir(), "hello.txt")
+	if err := os.WriteFile(src, []byte("hello"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Put(src, "docs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Put(src, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if string(m.Content("docs/hello.txt")) != "hello" || !m.Has("/hello.txt") {
+		t.Fatalf("expected the files to be stored, got %v", m.Keys())
+	}
+
+	dst := t.TempDir()
+	if err := m.Get(dst, "docs/hello.txt"); err != nil {
+		t.Fatal(err)
+	}
+	content, _ := os.ReadFile(filepath.Join(dst, "hello.txt"))
+	if string(content) != "hello" {
+		t.Errorf("expected hello, got %q", content)
+	}
+
+	if err := m.Get(dst, "missing.txt"); !os.IsNotExist(err) {
+		t.Errorf("expected a not exist error, got %v", err)
+	}
+}
+
+func TestMem_PutStream(t *testing.T) {
+	m := &Mem{}
+
+	err := m.PutStream(context.Background(), "a/b.txt", strings.NewReader("streamed"), filesystems.PutOptions{ContentType: "text/plain", Size: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file, ok := m.File("a/b.txt")
+	if !ok || string(file.Data) != "streamed" || file.ContentType != "text/plain" || file.LastModified.IsZero() {
+		t.Errorf("unexpected file %+v", file)
+	}
+
+	// changing what File returns must not change what is stored
+	file.Data[0] = 'X'
+	if string(m.Content("a/b.txt")) != "streamed" {
+		t.Error("expected the stored file to be left alone")
+	}
+}
+
+func TestMem_PutOptions(t *testing.T) {
+	m := &Mem{}
+
+	metadata := map[string]string{"owner": "42"}
+	err := m.PutStream(context.Background(), "report.pdf", strings.NewReader("%PDF"), filesystems.PutOptions{
+		Visibility:         filesystems.Public,
+		ContentType:        "application/pdf",
+		CacheControl:       "max-age=3600",
+		ContentDisposition: `attachment; filename="report.pdf"`,
+		Metadata:           metadata,
+		Size:               -1,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the metadata is copied, so changing it afterwards doesn't change the stored file
+	metadata["owner"] = "7"
+
+	file, _ := m.File("report.pdf")
+	if file.Visibility != filesystems.Public || file.CacheControl != "max-age=3600" ||
+		file.ContentDisposition != `attachment; filename="report.pdf"` || file.Metadata["owner"] != "42" {
+		t.Errorf("expected the options to be kept, got %+v", file)
+	}
+
+	src := filepath.Join(t.TempDir(), "page.html")
+	if err := os.WriteFile(src, []byte("<p>hi</p>"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Put(src, "pages"); err != nil {
+		t.Fatal(err)
+	}
+
+	file, _ = m.File("pages/page.html")
+	if file.Visibility != filesystems.Private || !strings.HasPrefix(file.ContentType, "text/html") {
+		t.Errorf("expected a private html file, got %+v", file)
+	}
+}
+
+func TestMem_ListDelete(t *testing.T) {
+	m := &Mem{}
+	for _, key := range []string{"a.txt", "images/b.png", "images/thumbs/c.png", "images/.hidden"} {
+		m.Set(key, []byte(key))
+	}
+
+	tests := []struct {
+		prefix string
+		keys   string
+	}{
+		{"", "a.txt,images/b.png,images/thumbs/c.png"},
+		{"/images/", "images/b.png,images/thumbs/c.png"},
+		{"images/t", "images/thumbs/c.png"},
+		{"missing", ""},
+	}
+
+	for _, e := range tests {
+		listing, err := m.List(e.prefix)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for _, item := range listing {
+			keys = append(keys, item.Key)
+		}
+		if strings.Join(keys, ",") != e.keys {
+			t.Errorf("%q: expected %s, got %v", e.prefix, e.keys, keys)
+		}
+	}
+
+	if !m.Delete([]string{"a.txt", "/images/b.png", "missing"}) {
+		t.Error("expected the files to be deleted")
+	}
+	if m.Len() != 2 || strings.Join(m.Keys(), ",") != "images/.hidden,images/thumbs/c.png" {
+		t.Errorf("unexpected files left: %v", m.Keys())
+	}
+
+	m.Reset()
+	if m.Len() != 0 {
+		t.Errorf("expected no files after a reset, got %v", m.Keys())
+	}
+}
+
+func TestMem_Streams(t *testing.T) {
+	m := &Mem{}
+	ctx := context.Background()
+
+	err := m.PutStream(ctx, "a.txt", strings.NewReader("hello"), filesystems.PutOptions{ContentType: "text/plain", Size: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err := m.Open(ctx, "a.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(reader)
+	_ = reader.Close()
+	if string(content) != "hello" {
+		t.Errorf("expected hello, got %q", content)
+	}
+
+	info, err := m.Stat(ctx, "/a.txt")
+	if err != nil || info.Key != "a.txt" || info.Size != 5 || info.ContentType != "text/plain" {
+		t.Errorf("unexpected info %+v, %v", info, err)
+	}
+
+	if err := m.Copy(ctx, "a.txt", "b/copy.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Move(ctx, "a.txt", "b/moved.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(m.Keys(), ",") != "b/copy.txt,b/moved.txt" {
+		t.Errorf("unexpected files %v", m.Keys())
+	}
+
+	for _, key := range []string{"a.txt", "b/copy.txt"} {
+		exists, err := m.Exists(ctx, key)
+		if err != nil || exists != (key != "a.txt") {
+			t.Errorf("%s: unexpected %v, %v", key, exists, err)
+		}
+	}
+
+	if _, err := m.Open(ctx, "a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+	if _, err := m.Stat(ctx, "a.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+	if err := m.Move(ctx, "a.txt", "c.txt"); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist, got %v", err)
+	}
+
+	if err := m.DeleteWithErrors(ctx, []string{"b/copy.txt", "b/moved.txt", "missing"}); err != nil || m.Len() != 0 {
+		t.Errorf("expected every file to be deleted, got %v, %v", m.Keys(), err)
+	}
+}
+
+func TestMem_Concurrent(t *testing.T) {
+	m := &Mem{}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := filepath.Join("files", string(rune('a'+i%26)), "f")
+			_ = m.PutStream(context.Background(), key, strings.NewReader("x"), filesystems.PutOptions{Size: -1})
+			_, _ = m.List("files")
+			m.Delete([]string{key})
+		}(i)
+	}
+	wg.Wait()
+}
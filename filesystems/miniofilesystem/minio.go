@@ -2,16 +2,39 @@ package miniofilesystem
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
 	"path"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/FernandoJVideira/velox/filesystems"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
+func init() {
+	filesystems.Register("minio", func(config filesystems.DiskConfig) (filesystems.FS, error) {
+		if config.Options["endpoint"] == "" || config.Options["bucket"] == "" {
+			return nil, errors.New("minio: endpoint and bucket have to be set")
+		}
+		useSSL, _ := strconv.ParseBool(config.Options["usessl"])
+		return &Minio{
+			Endpoint: config.Options["endpoint"],
+			Key:      config.Options["key"],
+			Secret:   config.Options["secret"],
+			UseSSL:   useSSL,
+			Region:   config.Options["region"],
+			Bucket:   config.Options["bucket"],
+		}, nil
+	})
+}
+
 type Minio struct {
 	Endpoint string
 	Key      string
@@ -21,33 +44,32 @@ type Minio struct {
 	Bucket   string
 }
 
-func (m *Minio) Put(fileName, folder string) error {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
-
-	objName := path.Base(fileName)
-	client := m.getCredentials()
-
-	uploadInfo, err := client.FPutObject(ctx, m.Bucket, fmt.Sprintf("%s/%s", folder, objName), fileName, minio.PutObjectOptions{})
+func (m *Minio) Put(fileName, folder string, opts ...filesystems.PutOptions) error {
+	f, err := os.Open(fileName)
 	if err != nil {
-		log.Println("Failed to upload")
-		log.Println(err)
-		log.Println("UploadInfo", uploadInfo)
+		return err
 	}
+	defer f.Close()
 
-	return nil
+	options, err := filesystems.FileOptions(f, opts...)
+	if err != nil {
+		return err
+	}
+	return m.PutStream(context.Background(), fmt.Sprintf("%s/%s", folder, path.Base(fileName)), f, options)
 }
 
 func (m *Minio) Get(destination string, items ...string) error {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	client := m.getCredentials()
+	client, err := m.newClient()
+	if err != nil {
+		return err
+	}
 
 	for _, item := range items {
 		err := client.FGetObject(ctx, m.Bucket, item, fmt.Sprintf("%s/%s", destination, path.Base(item)), minio.GetObjectOptions{})
 		if err != nil {
-			log.Println(err)
 			return err
 		}
 	}
@@ -60,7 +82,10 @@ func (m *Minio) List(prefix string) ([]filesystems.Listing, error) {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	client := m.getCredentials()
+	client, err := m.newClient()
+	if err != nil {
+		return listing, err
+	}
 
 	objectCh := client.ListObjects(ctx, m.Bucket, minio.ListObjectsOptions{
 		Prefix:    prefix,
@@ -69,7 +94,6 @@ func (m *Minio) List(prefix string) ([]filesystems.Listing, error) {
 
 	for object := range objectCh {
 		if object.Err != nil {
-			fmt.Println(object.Err)
 			return listing, object.Err
 		}
 
@@ -92,33 +116,174 @@ func (m *Minio) List(prefix string) ([]filesystems.Listing, error) {
 }
 
 func (m *Minio) Delete(itemsToDelete []string) bool {
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
+	return m.DeleteWithErrors(context.Background(), itemsToDelete) == nil
+}
 
-	client := m.getCredentials()
+func (m *Minio) PutStream(ctx context.Context, key string, r io.Reader, opts filesystems.PutOptions) error {
+	client, err := m.newClient()
+	if err != nil {
+		return err
+	}
+
+	metadata := make(map[string]string, len(opts.Metadata)+1)
+	for name, value := range opts.Metadata {
+		metadata[name] = value
+	}
+	// Minio itself has no ACLs, but S3 and other servers reached through it do
+	if opts.Visibility == filesystems.Public {
+		metadata["x-amz-acl"] = "public-read"
+	}
+
+	// minio takes -1 for a size that isn't known, and would store nothing for the zero value
+	size := opts.Size
+	if size <= 0 {
+		size = -1
+	}
+
+	_, err = client.PutObject(ctx, m.Bucket, key, r, size, minio.PutObjectOptions{
+		ContentType:        opts.ContentType,
+		CacheControl:       opts.CacheControl,
+		ContentDisposition: opts.ContentDisposition,
+		UserMetadata:       metadata,
+	})
+	return err
+}
+
+func (m *Minio) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	client, err := m.newClient()
+	if err != nil {
+		return nil, err
+	}
+
+	object, err := client.GetObject(ctx, m.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, notExist("open", key, err)
+	}
+	// the object is only requested once it is used, so it is checked first
+	_, err = object.Stat()
+	if err != nil {
+		_ = object.Close()
+		return nil, notExist("open", key, err)
+	}
+	return object, nil
+}
+
+func (m *Minio) Stat(ctx context.Context, key string) (filesystems.FileInfo, error) {
+	client, err := m.newClient()
+	if err != nil {
+		return filesystems.FileInfo{}, err
+	}
+
+	info, err := client.StatObject(ctx, m.Bucket, key, minio.StatObjectOptions{})
+	if err != nil {
+		return filesystems.FileInfo{}, notExist("stat", key, err)
+	}
+
+	return filesystems.FileInfo{
+		Key:          key,
+		Size:         info.Size,
+		LastModified: info.LastModified,
+		ContentType:  info.ContentType,
+		Etag:         info.ETag,
+	}, nil
+}
+
+func (m *Minio) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := m.Stat(ctx, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (m *Minio) Copy(ctx context.Context, src, dst string) error {
+	client, err := m.newClient()
+	if err != nil {
+		return err
+	}
+
+	_, err = client.CopyObject(ctx,
+		minio.CopyDestOptions{Bucket: m.Bucket, Object: dst},
+		minio.CopySrcOptions{Bucket: m.Bucket, Object: src},
+	)
+	return notExist("copy", src, err)
+}
+
+// Move copies the file and deletes the original, since objects can't be renamed
+func (m *Minio) Move(ctx context.Context, src, dst string) error {
+	err := m.Copy(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+	return m.DeleteWithErrors(ctx, []string{src})
+}
+
+func (m *Minio) DeleteWithErrors(ctx context.Context, keys []string) error {
+	client, err := m.newClient()
+	if err != nil {
+		return err
+	}
 
 	opts := minio.RemoveObjectOptions{
 		GovernanceBypass: true,
 	}
 
-	for _, item := range itemsToDelete {
-		err := client.RemoveObject(ctx, m.Bucket, item, opts)
+	var errs []error
+	for _, key := range keys {
+		err := client.RemoveObject(ctx, m.Bucket, key, opts)
 		if err != nil {
-			log.Println(err)
-			return false
+			errs = append(errs, err)
 		}
 	}
-	return true
+	return errors.Join(errs...)
+}
+
+func (m *Minio) TemporaryURL(key string, expiry time.Duration) (string, error) {
+	client, err := m.newClient()
+	if err != nil {
+		return "", err
+	}
+
+	u, err := client.PresignedGetObject(context.Background(), m.Bucket, key, expiry, nil)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func (m *Minio) TemporaryUploadURL(key string, expiry time.Duration, contentType string) (string, error) {
+	client, err := m.newClient()
+	if err != nil {
+		return "", err
+	}
+
+	headers := make(http.Header)
+	if contentType != "" {
+		headers.Set("Content-Type", contentType)
+	}
+
+	u, err := client.PresignHeader(context.Background(), http.MethodPut, m.Bucket, key, expiry, nil, headers)
+	if err != nil {
+		return "", err
+	}
+	return u.String(), nil
 }
 
-func (m *Minio) getCredentials() *minio.Client {
-	client, err := minio.New(m.Endpoint, &minio.Options{
+func (m *Minio) newClient() (*minio.Client, error) {
+	return minio.New(m.Endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(m.Key, m.Secret, ""),
 		Secure: m.UseSSL,
 	})
-	if err != nil {
-		log.Println(err)
-	}
+}
 
-	return client
+// notExist makes the errors Minio returns for missing objects wrap fs.ErrNotExist
+func notExist(op, key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	switch minio.ToErrorResponse(err).Code {
+	case "NoSuchKey", "NotFound":
+		return &fs.PathError{Op: op, Path: key, Err: fs.ErrNotExist}
+	}
+	return err
 }
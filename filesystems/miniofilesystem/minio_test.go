@@ -0,0 +1,84 @@
+package miniofilesystem
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+)
+
+// objectServer is an S3 server that only stores the objects put to it, whole or in parts, by path
+type objectServer struct {
+	mu      sync.Mutex
+	objects map[string]string
+	parts   map[string][]string
+}
+
+func (s *objectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case query.Has("location"):
+		_, _ = io.WriteString(w, `<LocationConstraint xmlns="http://s3.amazonaws.com/doc/2006-03-01/">us-east-1</LocationConstraint>`)
+	case r.Method == http.MethodPost && query.Has("uploads"):
+		s.parts[r.URL.Path] = nil
+		_, _ = io.WriteString(w, `<InitiateMultipartUploadResult><UploadId>upload</UploadId></InitiateMultipartUploadResult>`)
+	case r.Method == http.MethodPut && query.Has("partNumber"):
+		s.parts[r.URL.Path] = append(s.parts[r.URL.Path], string(body))
+		w.Header().Set("ETag", `"etag"`)
+	case r.Method == http.MethodPost && query.Has("uploadId"):
+		s.objects[r.URL.Path] = strings.Join(s.parts[r.URL.Path], "")
+		_, _ = io.WriteString(w, `<CompleteMultipartUploadResult><Bucket>files</Bucket><ETag>"etag"</ETag></CompleteMultipartUploadResult>`)
+	case r.Method == http.MethodPut:
+		s.objects[r.URL.Path] = string(body)
+		w.Header().Set("ETag", `"etag"`)
+	default:
+		http.Error(w, "not implemented", http.StatusNotImplemented)
+	}
+}
+
+func TestMinio_PutStreamSize(t *testing.T) {
+	server := &objectServer{objects: map[string]string{}, parts: map[string][]string{}}
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	m := &Minio{
+		Endpoint: strings.TrimPrefix(ts.URL, "http://"),
+		Key:      "key",
+		Secret:   "secret",
+		Bucket:   "files",
+	}
+
+	tests := []struct {
+		name string
+		size int64
+	}{
+		{"known", 11},
+		{"unknown", -1},
+		{"zero value", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := strings.ReplaceAll(tt.name, " ", "-") + ".txt"
+			err := m.PutStream(context.Background(), key, strings.NewReader("hello world"), filesystems.PutOptions{Size: tt.size})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			if got := server.objects["/files/"+key]; !strings.Contains(got, "hello world") {
+				t.Errorf("expected all of the file to be sent, got %q", got)
+			}
+		})
+	}
+}
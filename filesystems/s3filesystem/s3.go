@@ -1,11 +1,15 @@
 package s3filesystem
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"net/http"
+	"io"
+	"io/fs"
+	"net/url"
 	"os"
 	"path"
+	"time"
 
 	"github.com/FernandoJVideira/velox/filesystems"
 	"github.com/aws/aws-sdk-go/aws"
@@ -16,6 +20,24 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3/s3manager"
 )
 
+// allUsers is the grantee S3 uses for anyone, signed in or not
+const allUsers = "http://acs.amazonaws.com/groups/global/AllUsers"
+
+func init() {
+	filesystems.Register("s3", func(config filesystems.DiskConfig) (filesystems.FS, error) {
+		if config.Options["bucket"] == "" {
+			return nil, errors.New("s3: bucket is not set")
+		}
+		return &S3{
+			Key:      config.Options["key"],
+			Secret:   config.Options["secret"],
+			Region:   config.Options["region"],
+			Endpoint: config.Options["endpoint"],
+			Bucket:   config.Options["bucket"],
+		}, nil
+	})
+}
+
 type S3 struct {
 	Key      string
 	Secret   string
@@ -24,62 +46,26 @@ type S3 struct {
 	Bucket   string
 }
 
-func (s *S3) Put(fileName, folder string) error {
-	c := s.getCredentials()
-	sess := session.Must(session.NewSession(&aws.Config{
-		Endpoint:    &s.Endpoint,
-		Region:      &s.Region,
-		Credentials: c,
-	}))
-
-	uploader := s3manager.NewUploader(sess)
-
+func (s *S3) Put(fileName, folder string, opts ...filesystems.PutOptions) error {
 	f, err := os.Open(fileName)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	fileInfo, err := f.Stat()
+	options, err := filesystems.FileOptions(f, opts...)
 	if err != nil {
 		return err
 	}
+	return s.PutStream(context.Background(), fmt.Sprintf("%s/%s", folder, path.Base(fileName)), f, options)
+}
 
-	size := fileInfo.Size()
-
-	buffer := make([]byte, size)
-	_, err = f.Read(buffer)
-	if err != nil {
-		return err
-	}
-	fileBytes := bytes.NewReader(buffer)
-	fileType := http.DetectContentType(buffer)
-
-	_, err = uploader.Upload(&s3manager.UploadInput{
-		Bucket:      aws.String(s.Bucket),
-		Key:         aws.String(fmt.Sprintf("%s/%s", folder, path.Base(fileName))),
-		Body:        fileBytes,
-		ACL:         aws.String("public-read"),
-		ContentType: aws.String(fileType),
-		Metadata: map[string]*string{
-			"Key": aws.String("MetadataValue"),
-		},
-	})
+func (s *S3) Get(destination string, items ...string) error {
+	sess, err := s.newSession()
 	if err != nil {
 		return err
 	}
 
-	return nil
-}
-
-func (s *S3) Get(destination string, items ...string) error {
-	c := s.getCredentials()
-	sess := session.Must(session.NewSession(&aws.Config{
-		Endpoint:    &s.Endpoint,
-		Region:      &s.Region,
-		Credentials: c,
-	}))
-
 	for _, item := range items {
 		err := func() error {
 			file, err := os.Create(fmt.Sprintf("%s/%s", destination, path.Base(item)))
@@ -111,12 +97,10 @@ func (s *S3) List(prefix string) ([]filesystems.Listing, error) {
 		prefix = ""
 	}
 
-	c := s.getCredentials()
-	sess := session.Must(session.NewSession(&aws.Config{
-		Endpoint:    &s.Endpoint,
-		Region:      &s.Region,
-		Credentials: c,
-	}))
+	sess, err := s.newSession()
+	if err != nil {
+		return nil, err
+	}
 
 	// Create S3 service client
 	svc := s3.New(sess)
@@ -127,16 +111,6 @@ func (s *S3) List(prefix string) ([]filesystems.Listing, error) {
 
 	result, err := svc.ListObjects(input)
 	if err != nil {
-		if aerr, ok := err.(awserr.Error); ok {
-			switch aerr.Code() {
-			case s3.ErrCodeNoSuchBucket:
-				fmt.Println(s3.ErrCodeNoSuchBucket, aerr.Error())
-			default:
-				fmt.Println(aerr.Error())
-			}
-		} else {
-			fmt.Println(err.Error())
-		}
 		return nil, err
 	}
 
@@ -159,42 +133,230 @@ func (s *S3) List(prefix string) ([]filesystems.Listing, error) {
 }
 
 func (s *S3) Delete(itemsToDelete []string) bool {
-	c := s.getCredentials()
-	sess := session.Must(session.NewSession(&aws.Config{
-		Endpoint:    &s.Endpoint,
-		Region:      &s.Region,
-		Credentials: c,
-	}))
+	return s.DeleteWithErrors(context.Background(), itemsToDelete) == nil
+}
 
+func (s *S3) PutStream(ctx context.Context, key string, r io.Reader, opts filesystems.PutOptions) error {
+	sess, err := s.newSession()
+	if err != nil {
+		return err
+	}
+	uploader := s3manager.NewUploader(sess)
+
+	input := &s3manager.UploadInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+		Body:   r,
+	}
+	// private files get no ACL, so buckets with ACLs disabled accept them
+	if opts.Visibility == filesystems.Public {
+		input.ACL = aws.String(s3.ObjectCannedACLPublicRead)
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if opts.CacheControl != "" {
+		input.CacheControl = aws.String(opts.CacheControl)
+	}
+	if opts.ContentDisposition != "" {
+		input.ContentDisposition = aws.String(opts.ContentDisposition)
+	}
+	if len(opts.Metadata) > 0 {
+		input.Metadata = aws.StringMap(opts.Metadata)
+	}
+
+	_, err = uploader.UploadWithContext(ctx, input)
+	return err
+}
+
+func (s *S3) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	sess, err := s.newSession()
+	if err != nil {
+		return nil, err
+	}
 	svc := s3.New(sess)
 
-	for _, item := range itemsToDelete {
-		input := &s3.DeleteObjectsInput{
+	output, err := svc.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, notExist("open", key, err)
+	}
+	return output.Body, nil
+}
+
+func (s *S3) Stat(ctx context.Context, key string) (filesystems.FileInfo, error) {
+	sess, err := s.newSession()
+	if err != nil {
+		return filesystems.FileInfo{}, err
+	}
+	svc := s3.New(sess)
+
+	output, err := svc.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return filesystems.FileInfo{}, notExist("stat", key, err)
+	}
+
+	return filesystems.FileInfo{
+		Key:          key,
+		Size:         aws.Int64Value(output.ContentLength),
+		LastModified: aws.TimeValue(output.LastModified),
+		ContentType:  aws.StringValue(output.ContentType),
+		Etag:         aws.StringValue(output.ETag),
+	}, nil
+}
+
+func (s *S3) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.Stat(ctx, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Copy copies the file at src to dst, with the same visibility
+func (s *S3) Copy(ctx context.Context, src, dst string) error {
+	sess, err := s.newSession()
+	if err != nil {
+		return err
+	}
+	svc := s3.New(sess)
+
+	// copies don't keep the ACL of the source, so a public file is made public again
+	acl, err := svc.GetObjectAclWithContext(ctx, &s3.GetObjectAclInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(src),
+	})
+	if err != nil {
+		return notExist("copy", src, err)
+	}
+
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(s.Bucket),
+		CopySource: aws.String(url.PathEscape(s.Bucket + "/" + src)),
+		Key:        aws.String(dst),
+	}
+	if isPublic(acl.Grants) {
+		input.ACL = aws.String(s3.ObjectCannedACLPublicRead)
+	}
+
+	_, err = svc.CopyObjectWithContext(ctx, input)
+	return notExist("copy", src, err)
+}
+
+// isPublic reports whether the grants let anyone read the object
+func isPublic(grants []*s3.Grant) bool {
+	for _, grant := range grants {
+		if grant.Grantee == nil || aws.StringValue(grant.Grantee.URI) != allUsers {
+			continue
+		}
+		switch aws.StringValue(grant.Permission) {
+		case s3.PermissionRead, s3.PermissionFullControl:
+			return true
+		}
+	}
+	return false
+}
+
+// Move copies the file and deletes the original, since objects can't be renamed
+func (s *S3) Move(ctx context.Context, src, dst string) error {
+	err := s.Copy(ctx, src, dst)
+	if err != nil {
+		return err
+	}
+	return s.DeleteWithErrors(ctx, []string{src})
+}
+
+func (s *S3) DeleteWithErrors(ctx context.Context, keys []string) error {
+	sess, err := s.newSession()
+	if err != nil {
+		return err
+	}
+	svc := s3.New(sess)
+
+	var errs []error
+	// a request deletes at most 1000 objects
+	for start := 0; start < len(keys); start += 1000 {
+		end := min(start+1000, len(keys))
+
+		var objects []*s3.ObjectIdentifier
+		for _, key := range keys[start:end] {
+			objects = append(objects, &s3.ObjectIdentifier{Key: aws.String(key)})
+		}
+
+		output, err := svc.DeleteObjectsWithContext(ctx, &s3.DeleteObjectsInput{
 			Bucket: aws.String(s.Bucket),
 			Delete: &s3.Delete{
-				Objects: []*s3.ObjectIdentifier{
-					{
-						Key: aws.String(item),
-					},
-				},
-				Quiet: aws.Bool(true),
+				Objects: objects,
+				Quiet:   aws.Bool(true),
 			},
-		}
-		_, err := svc.DeleteObjects(input)
+		})
 		if err != nil {
-			if aerr, ok := err.(awserr.Error); ok {
-				switch aerr.Code() {
-				default:
-					fmt.Println("Amazon Error: ", aerr.Error())
-					return false
-				}
-			} else {
-				fmt.Println("Other Error: ", err.Error())
-				return false
-			}
+			errs = append(errs, err)
+			continue
+		}
+		for _, e := range output.Errors {
+			errs = append(errs, fmt.Errorf("%s: %s", aws.StringValue(e.Key), aws.StringValue(e.Message)))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *S3) TemporaryURL(key string, expiry time.Duration) (string, error) {
+	sess, err := s.newSession()
+	if err != nil {
+		return "", err
+	}
+	svc := s3.New(sess)
+
+	req, _ := svc.GetObjectRequest(&s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	})
+	return req.Presign(expiry)
+}
+
+func (s *S3) TemporaryUploadURL(key string, expiry time.Duration, contentType string) (string, error) {
+	sess, err := s.newSession()
+	if err != nil {
+		return "", err
+	}
+	svc := s3.New(sess)
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(key),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+
+	req, _ := svc.PutObjectRequest(input)
+	return req.Presign(expiry)
+}
+
+func (s *S3) newSession() (*session.Session, error) {
+	return session.NewSession(&aws.Config{
+		Endpoint:    &s.Endpoint,
+		Region:      &s.Region,
+		Credentials: s.getCredentials(),
+	})
+}
+
+// notExist makes the errors S3 returns for missing objects wrap fs.ErrNotExist
+func notExist(op, key string, err error) error {
+	var aerr awserr.Error
+	if errors.As(err, &aerr) {
+		switch aerr.Code() {
+		case s3.ErrCodeNoSuchKey, "NotFound":
+			return &fs.PathError{Op: op, Path: key, Err: fs.ErrNotExist}
 		}
 	}
-	return true
+	return err
 }
 
 func (s *S3) getCredentials() *credentials.Credentials {
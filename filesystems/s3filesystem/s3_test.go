@@ -0,0 +1,38 @@
+package s3filesystem
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/s3"
+)
+
+func TestIsPublic(t *testing.T) {
+	grant := func(uri, permission string) *s3.Grant {
+		return &s3.Grant{
+			Grantee:    &s3.Grantee{Type: aws.String(s3.TypeGroup), URI: aws.String(uri)},
+			Permission: aws.String(permission),
+		}
+	}
+	owner := &s3.Grant{
+		Grantee:    &s3.Grantee{Type: aws.String(s3.TypeCanonicalUser), ID: aws.String("owner")},
+		Permission: aws.String(s3.PermissionFullControl),
+	}
+
+	tests := []struct {
+		name   string
+		grants []*s3.Grant
+		public bool
+	}{
+		{"owner only", []*s3.Grant{owner}, false},
+		{"public read", []*s3.Grant{owner, grant(allUsers, s3.PermissionRead)}, true},
+		{"public write only", []*s3.Grant{owner, grant(allUsers, s3.PermissionWrite)}, false},
+		{"authenticated users", []*s3.Grant{owner, grant("http://acs.amazonaws.com/groups/global/AuthenticatedUsers", s3.PermissionRead)}, false},
+	}
+
+	for _, tt := range tests {
+		if got := isPublic(tt.grants); got != tt.public {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.public, got)
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package sftpfilesystem
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
+	"io/fs"
 	"os"
 	"path"
 	"strings"
@@ -13,6 +15,24 @@ import (
 	"golang.org/x/crypto/ssh"
 )
 
+func init() {
+	filesystems.Register("sftp", func(config filesystems.DiskConfig) (filesystems.FS, error) {
+		if config.Options["host"] == "" {
+			return nil, errors.New("sftp: host is not set")
+		}
+		port := config.Options["port"]
+		if port == "" {
+			port = "22"
+		}
+		return &SFTP{
+			Host: config.Options["host"],
+			User: config.Options["user"],
+			Pass: config.Options["pass"],
+			Port: port,
+		}, nil
+	})
+}
+
 type SFTP struct {
 	Host string
 	User string
@@ -20,30 +40,18 @@ type SFTP struct {
 	Port string
 }
 
-func (s *SFTP) Put(fileName, folder string) error {
-	client, err := s.getCredentials()
-	if err != nil {
-		return err
-	}
-	defer client.Close()
-
+func (s *SFTP) Put(fileName, folder string, opts ...filesystems.PutOptions) error {
 	f, err := os.Open(fileName)
 	if err != nil {
 		return err
 	}
 	defer f.Close()
 
-	destFile, err := client.Create(fmt.Sprintf("%s/%s", folder, path.Base(fileName)))
+	options, err := filesystems.FileOptions(f, opts...)
 	if err != nil {
 		return err
 	}
-	defer destFile.Close()
-
-	if _, err := f.WriteTo(destFile); err != nil {
-		return err
-	}
-
-	return nil
+	return s.PutStream(context.Background(), fmt.Sprintf("%s/%s", folder, path.Base(fileName)), f, options)
 }
 
 func (s *SFTP) Get(destination string, items ...string) error {
@@ -121,23 +129,191 @@ func (s *SFTP) List(prefix string) ([]filesystems.Listing, error) {
 }
 
 func (s *SFTP) Delete(itemsToDelete []string) bool {
+	return s.DeleteWithErrors(context.Background(), itemsToDelete) == nil
+}
+
+func (s *SFTP) PutStream(ctx context.Context, key string, r io.Reader, opts filesystems.PutOptions) error {
 	client, err := s.getCredentials()
 	if err != nil {
-		return false
+		return err
 	}
 	defer client.Close()
 
-	for _, item := range itemsToDelete {
-		err := client.Remove(item)
-		if err != nil {
-			return false
+	return putFile(client.Client, key, r, opts.Visibility.FileMode())
+}
+
+func (s *SFTP) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	client, err := s.getCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := client.Open(key)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+
+	// the connection is kept open until the file is read
+	return &remoteFile{File: file, client: client}, nil
+}
+
+func (s *SFTP) Stat(ctx context.Context, key string) (filesystems.FileInfo, error) {
+	client, err := s.getCredentials()
+	if err != nil {
+		return filesystems.FileInfo{}, err
+	}
+	defer client.Close()
+
+	info, err := client.Stat(key)
+	if err != nil {
+		return filesystems.FileInfo{}, err
+	}
+	if info.IsDir() {
+		return filesystems.FileInfo{}, &fs.PathError{Op: "stat", Path: key, Err: fs.ErrNotExist}
+	}
+
+	return filesystems.FileInfo{
+		Key:          key,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+func (s *SFTP) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := s.Stat(ctx, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *SFTP) Copy(ctx context.Context, src, dst string) error {
+	client, err := s.getCredentials()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	srcFile, err := client.Open(src)
+	if err != nil {
+		return err
+	}
+	defer srcFile.Close()
+
+	info, err := srcFile.Stat()
+	if err != nil {
+		return err
+	}
+	return putFile(client.Client, dst, srcFile, info.Mode().Perm())
+}
+
+func (s *SFTP) Move(ctx context.Context, src, dst string) error {
+	client, err := s.getCredentials()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	err = client.MkdirAll(path.Dir(dst))
+	if err != nil {
+		return err
+	}
+	return replace(client.Client, src, dst)
+}
+
+func (s *SFTP) DeleteWithErrors(ctx context.Context, keys []string) error {
+	client, err := s.getCredentials()
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var errs []error
+	for _, key := range keys {
+		err := client.Remove(key)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// putFile writes the file read from r at key with the permissions mode, creating its folder. The
+// file is written under a temporary name and renamed once all of it is written
+func putFile(client *sftp.Client, key string, r io.Reader, mode os.FileMode) error {
+	err := client.MkdirAll(path.Dir(key))
+	if err != nil {
+		return err
+	}
+
+	tmp, err := filesystems.TempKey(key)
+	if err != nil {
+		return err
+	}
+	file, err := client.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	err = file.Chmod(mode)
+	if err == nil {
+		_, err = file.ReadFrom(r)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = replace(client, tmp, key)
+	}
+	if err != nil {
+		_ = client.Remove(tmp)
+	}
+	return err
+}
+
+// replace renames src to dst, replacing dst if it exists
+func replace(client *sftp.Client, src, dst string) error {
+	// a plain rename fails when dst exists, but not every server can replace files
+	err := client.PosixRename(src, dst)
+	if err != nil {
+		if _, statErr := client.Stat(src); statErr != nil {
+			return statErr
 		}
+		_ = client.Remove(dst)
+		err = client.Rename(src, dst)
+	}
+	return err
+}
+
+// connection is an SFTP client that closes its SSH connection when it is closed, which the SFTP
+// client alone doesn't do
+type connection struct {
+	*sftp.Client
+	ssh *ssh.Client
+}
+
+func (c *connection) Close() error {
+	err := c.Client.Close()
+	if sshErr := c.ssh.Close(); err == nil {
+		err = sshErr
 	}
+	return err
+}
+
+// remoteFile closes the connection it was opened with once it is closed
+type remoteFile struct {
+	*sftp.File
+	client *connection
+}
 
-	return true
+func (r *remoteFile) Close() error {
+	err := r.File.Close()
+	_ = r.client.Close()
+	return err
 }
 
-func (s *SFTP) getCredentials() (*sftp.Client, error) {
+func (s *SFTP) getCredentials() (*connection, error) {
 	addr := fmt.Sprintf("%s:%s", s.Host, s.Port)
 	config := ssh.ClientConfig{
 		User: s.User,
@@ -154,14 +330,9 @@ func (s *SFTP) getCredentials() (*sftp.Client, error) {
 
 	client, err := sftp.NewClient(conn)
 	if err != nil {
+		_ = conn.Close()
 		return nil, err
 	}
 
-	cwd, err := client.Getwd()
-	log.Printf("Current working directory: %s", cwd)
-	if err != nil {
-		return nil, err
-	}
-
-	return client, nil
+	return &connection{Client: client, ssh: conn}, nil
 }
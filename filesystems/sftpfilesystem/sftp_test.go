@@ -0,0 +1,80 @@
+package sftpfilesystem
+
+import (
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/pkg/sftp"
+)
+
+// testClient returns a client of an in-memory SFTP server
+func testClient(t *testing.T) *sftp.Client {
+	t.Helper()
+	serverConn, clientConn := net.Pipe()
+	server := sftp.NewRequestServer(serverConn, sftp.InMemHandler())
+	go func() { _ = server.Serve() }()
+
+	client, err := sftp.NewClientPipe(clientConn, clientConn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+		_ = server.Close()
+	})
+	return client
+}
+
+// failingReader returns its data, and then fails
+type failingReader struct {
+	r io.Reader
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestPutFile(t *testing.T) {
+	client := testClient(t)
+
+	err := putFile(client, "/videos/clip.txt", strings.NewReader("first"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = putFile(client, "/videos/clip.txt", strings.NewReader("second"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = putFile(client, "/videos/clip.txt", &failingReader{strings.NewReader("trunc")}, 0644)
+	if err == nil {
+		t.Fatal("expected the error of the reader")
+	}
+
+	file, err := client.Open("/videos/clip.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	content, _ := io.ReadAll(file)
+	if string(content) != "second" {
+		t.Errorf("expected a failed write to leave the file alone, got %q", content)
+	}
+
+	entries, err := client.ReadDir("/videos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "clip.txt" {
+			t.Errorf("expected the temporary files to be removed, found %s", entry.Name())
+		}
+	}
+}
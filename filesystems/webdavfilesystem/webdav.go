@@ -1,8 +1,12 @@
 package webdavfilesystem
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"io/fs"
+	"net/http"
 	"os"
 	"path"
 	"strings"
@@ -11,27 +15,37 @@ import (
 	"github.com/studio-b12/gowebdav"
 )
 
+func init() {
+	filesystems.Register("webdav", func(config filesystems.DiskConfig) (filesystems.FS, error) {
+		if config.Options["host"] == "" {
+			return nil, errors.New("webdav: host is not set")
+		}
+		return &WebDAV{
+			Host: config.Options["host"],
+			User: config.Options["user"],
+			Pass: config.Options["pass"],
+		}, nil
+	})
+}
+
 type WebDAV struct {
 	Host string
 	User string
 	Pass string
 }
 
-func (w *WebDAV) Put(fileName, folder string) error {
-	client := w.getCredentials()
-
+func (w *WebDAV) Put(fileName, folder string, opts ...filesystems.PutOptions) error {
 	file, err := os.Open(fileName)
 	if err != nil {
 		return err
 	}
 	defer file.Close()
 
-	err = client.WriteStream(fmt.Sprintf("%s/%s", folder, path.Base(fileName)), file, 0644)
+	options, err := filesystems.FileOptions(file, opts...)
 	if err != nil {
 		return err
 	}
-
-	return nil
+	return w.PutStream(context.Background(), fmt.Sprintf("%s/%s", folder, path.Base(fileName)), file, options)
 }
 
 func (w *WebDAV) Get(destination string, items ...string) error {
@@ -96,15 +110,118 @@ func (w *WebDAV) List(prefix string) ([]filesystems.Listing, error) {
 }
 
 func (w *WebDAV) Delete(itemsToDelete []string) bool {
+	return w.DeleteWithErrors(context.Background(), itemsToDelete) == nil
+}
+
+// PutStream sends the content type, cache control and content disposition along with the file,
+// which servers may keep and serve it with. The file is sent under a temporary name and moved to
+// key once all of it is sent
+func (w *WebDAV) PutStream(ctx context.Context, key string, r io.Reader, opts filesystems.PutOptions) error {
 	client := w.getCredentials()
 
-	for _, item := range itemsToDelete {
-		err := client.Remove(item)
+	tmp, err := filesystems.TempKey(key)
+	if err != nil {
+		return err
+	}
+
+	headers := map[string]string{
+		"Content-Type":        opts.ContentType,
+		"Cache-Control":       opts.CacheControl,
+		"Content-Disposition": opts.ContentDisposition,
+	}
+	// the headers are only sent with the file, not when its folders are made
+	client.SetInterceptor(func(method string, rq *http.Request) {
+		if method != http.MethodPut {
+			return
+		}
+		for name, value := range headers {
+			if value != "" {
+				rq.Header.Set(name, value)
+			}
+		}
+	})
+
+	err = client.WriteStream(tmp, r, 0644)
+	if err == nil {
+		err = client.Rename(tmp, key, true)
+	}
+	if err != nil {
+		_ = client.Remove(tmp)
+	}
+	return err
+}
+
+func (w *WebDAV) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	client := w.getCredentials()
+
+	reader, err := client.ReadStream(key)
+	if err != nil {
+		return nil, notExist("open", key, err)
+	}
+	return reader, nil
+}
+
+func (w *WebDAV) Stat(ctx context.Context, key string) (filesystems.FileInfo, error) {
+	client := w.getCredentials()
+
+	info, err := client.Stat(key)
+	if err != nil {
+		return filesystems.FileInfo{}, notExist("stat", key, err)
+	}
+	if info.IsDir() {
+		return filesystems.FileInfo{}, &fs.PathError{Op: "stat", Path: key, Err: fs.ErrNotExist}
+	}
+
+	fileInfo := filesystems.FileInfo{
+		Key:          key,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+	}
+	if file, ok := info.(*gowebdav.File); ok {
+		fileInfo.ContentType = file.ContentType()
+		fileInfo.Etag = file.ETag()
+	}
+	return fileInfo, nil
+}
+
+func (w *WebDAV) Exists(ctx context.Context, key string) (bool, error) {
+	_, err := w.Stat(ctx, key)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (w *WebDAV) Copy(ctx context.Context, src, dst string) error {
+	client := w.getCredentials()
+	return notExist("copy", src, client.Copy(src, dst, true))
+}
+
+func (w *WebDAV) Move(ctx context.Context, src, dst string) error {
+	client := w.getCredentials()
+	return notExist("move", src, client.Rename(src, dst, true))
+}
+
+// DeleteWithErrors removes files; the server ignores the ones that don't exist
+func (w *WebDAV) DeleteWithErrors(ctx context.Context, keys []string) error {
+	client := w.getCredentials()
+
+	var errs []error
+	for _, key := range keys {
+		err := client.Remove(key)
 		if err != nil {
-			return false
+			errs = append(errs, err)
 		}
 	}
-	return true
+	return errors.Join(errs...)
+}
+
+// notExist makes the errors returned for missing files wrap fs.ErrNotExist
+func notExist(op, key string, err error) error {
+	if gowebdav.IsErrNotFound(err) {
+		return &fs.PathError{Op: op, Path: key, Err: fs.ErrNotExist}
+	}
+	return err
 }
 
 func (w *WebDAV) getCredentials() *gowebdav.Client {
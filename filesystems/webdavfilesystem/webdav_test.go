@@ -0,0 +1,69 @@
+package webdavfilesystem
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+	"golang.org/x/net/webdav"
+)
+
+// failingReader returns its data, and then fails
+type failingReader struct {
+	r io.Reader
+}
+
+func (f *failingReader) Read(p []byte) (int, error) {
+	n, err := f.r.Read(p)
+	if err == io.EOF {
+		return n, errors.New("connection reset")
+	}
+	return n, err
+}
+
+func TestWebDAV_PutStream(t *testing.T) {
+	server := httptest.NewServer(&webdav.Handler{
+		FileSystem: webdav.NewMemFS(),
+		LockSystem: webdav.NewMemLS(),
+	})
+	defer server.Close()
+
+	w := &WebDAV{Host: server.URL}
+	ctx := context.Background()
+
+	for _, content := range []string{"first", "second"} {
+		err := w.PutStream(ctx, "/videos/clip.txt", strings.NewReader(content), filesystems.PutOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := w.PutStream(ctx, "/videos/clip.txt", &failingReader{strings.NewReader("trunc")}, filesystems.PutOptions{})
+	if err == nil {
+		t.Fatal("expected the error of the reader")
+	}
+
+	file, err := w.Open(ctx, "/videos/clip.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	content, _ := io.ReadAll(file)
+	if string(content) != "second" {
+		t.Errorf("expected a failed write to leave the file alone, got %q", content)
+	}
+
+	entries, err := w.getCredentials().ReadDir("/videos")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != "clip.txt" {
+			t.Errorf("expected the temporary files to be removed, found %s", entry.Name())
+		}
+	}
+}
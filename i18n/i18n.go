@@ -0,0 +1,376 @@
+package i18n
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/BurntSushi/toml"
+)
+
+type contextKey string
+
+const localeKey contextKey = "velox_locale"
+
+// Translator holds the translations of every locale, loaded from json or toml files
+type Translator struct {
+	DefaultLocale string
+	messages      map[string]map[string]Message
+	mu            sync.RWMutex
+}
+
+// Message is a translated string, with one entry per plural form it needs
+// (zero, one, two, few, many, other). Messages without plural forms only use other
+type Message map[string]string
+
+var pluralForms = map[string]bool{"zero": true, "one": true, "two": true, "few": true, "many": true, "other": true}
+
+// New returns an empty translator that falls back to defaultLocale
+func New(defaultLocale string) *Translator {
+	if defaultLocale == "" {
+		defaultLocale = "en"
+	}
+	return &Translator{
+		DefaultLocale: normalize(defaultLocale),
+		messages:      make(map[string]map[string]Message),
+	}
+}
+
+// LoadDir loads every translation file in path. Files are named after their locale, e.g.
+// lang/en.json or lang/pt-BR.toml, or live in a folder named after it, e.g. lang/en/auth.json.
+// A missing folder is not an error
+func (t *Translator) LoadDir(path string) error {
+	entries, err := os.ReadDir(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		if entry.IsDir() {
+			files, err := os.ReadDir(filepath.Join(path, entry.Name()))
+			if err != nil {
+				return err
+			}
+			for _, file := range files {
+				if !file.IsDir() {
+					err = t.LoadFile(entry.Name(), filepath.Join(path, entry.Name(), file.Name()))
+					if err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+
+		locale := strings.TrimSuffix(entry.Name(), filepath.Ext(entry.Name()))
+		err = t.LoadFile(locale, filepath.Join(path, entry.Name()))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// LoadFile loads the translations of a locale from a json or toml file. Other files are ignored
+func (t *Translator) LoadFile(locale, file string) error {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+
+	data := make(map[string]interface{})
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		err = json.Unmarshal(content, &data)
+	case ".toml":
+		err = toml.Unmarshal(content, &data)
+	default:
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+
+	t.Add(locale, data)
+	return nil
+}
+
+// Add adds translations to a locale. Nested objects become dotted keys ("auth.login.title"),
+// except objects made only of plural forms, which become a plural message
+func (t *Translator) Add(locale string, data map[string]interface{}) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	locale = normalize(locale)
+	if t.messages[locale] == nil {
+		t.messages[locale] = make(map[string]Message)
+	}
+	flatten(t.messages[locale], "", data)
+}
+
+// Locales returns the locales with translations, sorted
+func (t *Translator) Locales() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	var locales []string
+	for locale := range t.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Supports reports whether there are translations for locale
+func (t *Translator) Supports(locale string) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	_, ok := t.messages[normalize(locale)]
+	return ok
+}
+
+// Match returns the first candidate locale with translations, also trying the base language
+// of each one (pt-BR matches pt). It returns an empty string when nothing matches
+func (t *Translator) Match(candidates ...string) string {
+	for _, candidate := range candidates {
+		for _, locale := range fallbacks(candidate) {
+			if t.Supports(locale) {
+				return locale
+			}
+		}
+	}
+	return ""
+}
+
+// T translates key into locale, falling back to the base language and then the default locale.
+// Arguments are given as name/value pairs or as a single map, and replace {name} placeholders;
+// a "count" argument selects the plural form. When no translation exists the key is returned
+func (t *Translator) T(locale, key string, args ...interface{}) string {
+	text, ok := t.Translate(locale, key, args...)
+	if !ok {
+		return key
+	}
+	return text
+}
+
+// Translate works like T, but reports whether a translation was found
+func (t *Translator) Translate(locale, key string, args ...interface{}) (string, bool) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	params := arguments(args)
+
+	for _, l := range append(fallbacks(locale), t.DefaultLocale) {
+		msg, ok := t.messages[l][key]
+		if !ok {
+			continue
+		}
+
+		text := msg["other"]
+		if count, ok := toInt(params["count"]); ok {
+			text = msg.form(l, count)
+		}
+
+		return interpolate(text, params), true
+	}
+
+	return "", false
+}
+
+// form returns the plural form of the message for count
+func (m Message) form(locale string, count int) string {
+	if text, ok := m["zero"]; ok && count == 0 {
+		return text
+	}
+	if text, ok := m[PluralCategory(locale, count)]; ok {
+		return text
+	}
+	return m["other"]
+}
+
+// WithLocale returns a copy of ctx carrying locale
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey, locale)
+}
+
+// LocaleFromContext returns the locale stored in ctx by WithLocale, or an empty string
+func LocaleFromContext(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey).(string)
+	return locale
+}
+
+// ParseAcceptLanguage returns the languages of an Accept-Language header, most preferred first
+func ParseAcceptLanguage(header string) []string {
+	type language struct {
+		tag     string
+		quality float64
+	}
+	var languages []language
+
+	for _, part := range strings.Split(header, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		lang := language{tag: part, quality: 1}
+		if i := strings.Index(part, ";"); i >= 0 {
+			lang.tag = strings.TrimSpace(part[:i])
+			var q float64
+			if _, err := fmt.Sscanf(strings.TrimSpace(part[i+1:]), "q=%g", &q); err == nil {
+				lang.quality = q
+			}
+		}
+		if lang.tag != "*" && lang.quality > 0 {
+			languages = append(languages, lang)
+		}
+	}
+
+	sort.SliceStable(languages, func(i, j int) bool {
+		return languages[i].quality > languages[j].quality
+	})
+
+	tags := make([]string, len(languages))
+	for i, l := range languages {
+		tags[i] = l.tag
+	}
+	return tags
+}
+
+// flatten adds data to messages, joining the keys of nested objects with dots
+func flatten(messages map[string]Message, prefix string, data map[string]interface{}) {
+	for key, value := range data {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+
+		switch v := value.(type) {
+		case string:
+			messages[key] = Message{"other": v}
+		case map[string]interface{}:
+			if isPlural(v) {
+				msg := make(Message)
+				for form, text := range v {
+					msg[form] = fmt.Sprint(text)
+				}
+				if _, ok := msg["other"]; !ok {
+					msg["other"] = msg["one"]
+				}
+				messages[key] = msg
+			} else {
+				flatten(messages, key, v)
+			}
+		default:
+			messages[key] = Message{"other": fmt.Sprint(v)}
+		}
+	}
+}
+
+func isPlural(data map[string]interface{}) bool {
+	if len(data) == 0 {
+		return false
+	}
+	for key, value := range data {
+		if _, ok := value.(string); !ok || !pluralForms[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// arguments turns name/value pairs, or a single map, into a map of parameters
+func arguments(args []interface{}) map[string]interface{} {
+	params := make(map[string]interface{})
+	if len(args) == 1 {
+		switch m := args[0].(type) {
+		case map[string]interface{}:
+			return m
+		case map[string]string:
+			for k, v := range m {
+				params[k] = v
+			}
+			return params
+		}
+	}
+
+	for i := 0; i+1 < len(args); i += 2 {
+		params[fmt.Sprint(args[i])] = args[i+1]
+	}
+	return params
+}
+
+// interpolate replaces {name} placeholders in text with their parameter
+func interpolate(text string, params map[string]interface{}) string {
+	if len(params) == 0 || !strings.Contains(text, "{") {
+		return text
+	}
+
+	pairs := make([]string, 0, len(params)*2)
+	for name, value := range params {
+		pairs = append(pairs, "{"+name+"}", fmt.Sprint(value))
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+func toInt(value interface{}) (int, bool) {
+	switch n := value.(type) {
+	case int:
+		return n, true
+	case int8:
+		return int(n), true
+	case int16:
+		return int(n), true
+	case int32:
+		return int(n), true
+	case int64:
+		return int(n), true
+	case uint:
+		return int(n), true
+	case uint8:
+		return int(n), true
+	case uint16:
+		return int(n), true
+	case uint32:
+		return int(n), true
+	case uint64:
+		return int(n), true
+	case float32:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
+
+// fallbacks returns locale followed by its base language, e.g. pt-BR and pt
+func fallbacks(locale string) []string {
+	locale = normalize(locale)
+	if locale == "" {
+		return nil
+	}
+	if i := strings.Index(locale, "-"); i > 0 {
+		return []string{locale, locale[:i]}
+	}
+	return []string{locale}
+}
+
+// normalize turns locales such as pt_br or PT-br into pt-BR
+func normalize(locale string) string {
+	locale = strings.TrimSpace(strings.ReplaceAll(locale, "_", "-"))
+	parts := strings.SplitN(locale, "-", 2)
+	parts[0] = strings.ToLower(parts[0])
+	if len(parts) == 2 {
+		parts[1] = strings.ToUpper(parts[1])
+	}
+	return strings.Join(parts, "-")
+}
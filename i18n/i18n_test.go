@@ -0,0 +1,123 @@
+package i18n
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+var translationTests = []struct {
+	name     string
+	locale   string
+	key      string
+	args     []interface{}
+	expected string
+}{
+	{"json", "en", "welcome", []interface{}{"name", "Ana"}, "Welcome, Ana!"},
+	{"toml", "pt", "welcome", []interface{}{"name", "Ana"}, "Bem-vindo, Ana!"},
+	{"folder", "fr", "welcome", []interface{}{"name", "Ana"}, "Bienvenue, Ana !"},
+	{"map_args", "en", "welcome", []interface{}{map[string]interface{}{"name": "Rui"}}, "Welcome, Rui!"},
+	{"nested_key", "en", "auth.login.title", nil, "Log in"},
+	{"nested_toml_key", "pt", "auth.login.title", nil, "Entrar"},
+	{"plural_one", "en", "apples", []interface{}{"count", 1}, "1 apple"},
+	{"plural_other", "en", "apples", []interface{}{"count", 5}, "5 apples"},
+	{"plural_zero", "en", "apples", []interface{}{"count", 0}, "No apples"},
+	{"plural_pt_zero", "pt", "apples", []interface{}{"count", 0}, "0 maçã"},
+	{"region_fallback", "pt-BR", "welcome", []interface{}{"name", "Ana"}, "Bem-vindo, Ana!"},
+	{"default_fallback", "fr", "auth.login.title", nil, "Log in"},
+	{"unknown_locale", "de", "auth.login.title", nil, "Log in"},
+	{"missing_key", "en", "does.not.exist", nil, "does.not.exist"},
+}
+
+func TestTranslator_T(t *testing.T) {
+	for _, e := range translationTests {
+		got := testTranslator.T(e.locale, e.key, e.args...)
+		if got != e.expected {
+			t.Errorf("%s: expected %q, got %q", e.name, e.expected, got)
+		}
+	}
+}
+
+func TestTranslator_Translate(t *testing.T) {
+	if _, ok := testTranslator.Translate("en", "does.not.exist"); ok {
+		t.Error("missing translation reported as found")
+	}
+	if _, ok := testTranslator.Translate("en", "welcome"); !ok {
+		t.Error("existing translation reported as missing")
+	}
+}
+
+func TestTranslator_Locales(t *testing.T) {
+	expected := []string{"en", "fr", "pt"}
+	if got := testTranslator.Locales(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestTranslator_Match(t *testing.T) {
+	tests := []struct {
+		candidates []string
+		expected   string
+	}{
+		{[]string{"pt-BR", "en"}, "pt"},
+		{[]string{"de", "fr-CA"}, "fr"},
+		{[]string{"EN_us"}, "en"},
+		{[]string{"de"}, ""},
+	}
+
+	for _, e := range tests {
+		if got := testTranslator.Match(e.candidates...); got != e.expected {
+			t.Errorf("%v: expected %q, got %q", e.candidates, e.expected, got)
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	got := ParseAcceptLanguage("fr-CH, fr;q=0.9, en;q=0.8, de;q=0.95, *;q=0.5, es;q=0")
+	expected := []string{"fr-CH", "de", "fr", "en"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if len(ParseAcceptLanguage("")) != 0 {
+		t.Error("empty header should have no languages")
+	}
+}
+
+func TestLocaleContext(t *testing.T) {
+	ctx := WithLocale(context.Background(), "pt")
+	if LocaleFromContext(ctx) != "pt" {
+		t.Error("locale not stored in context")
+	}
+	if LocaleFromContext(context.Background()) != "" {
+		t.Error("expected empty locale for a context without one")
+	}
+}
+
+func TestPluralCategory(t *testing.T) {
+	tests := []struct {
+		locale   string
+		count    int
+		expected string
+	}{
+		{"en", 1, "one"},
+		{"en", 0, "other"},
+		{"en-GB", 2, "other"},
+		{"fr", 0, "one"},
+		{"ru", 1, "one"},
+		{"ru", 3, "few"},
+		{"ru", 11, "many"},
+		{"ru", 22, "few"},
+		{"pl", 5, "many"},
+		{"cs", 3, "few"},
+		{"ja", 1, "other"},
+		{"ar", 2, "two"},
+		{"ar", 105, "few"},
+	}
+
+	for _, e := range tests {
+		if got := PluralCategory(e.locale, e.count); got != e.expected {
+			t.Errorf("%s %d: expected %s, got %s", e.locale, e.count, e.expected, got)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package i18n
+
+import (
+	"log"
+	"os"
+	"testing"
+)
+
+var testTranslator = New("en")
+
+func TestMain(m *testing.M) {
+	err := testTranslator.LoadDir("./testdata/lang")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	os.Exit(m.Run())
+}
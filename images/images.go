@@ -0,0 +1,273 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"strconv"
+	"strings"
+
+	"github.com/HugoSmits86/nativewebp"
+	"github.com/disintegration/imaging"
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	JPEG = "jpeg"
+	PNG  = "png"
+	WebP = "webp"
+)
+
+var (
+	ErrUnsupported = errors.New("images: unsupported image format")
+	ErrTooLarge    = errors.New("images: image is too large")
+)
+
+// defaultMaxPixels bounds the size of the images decoded, so a small file can't claim huge
+// dimensions and exhaust memory
+const defaultMaxPixels = 50_000_000
+
+// Variant is a resized copy of an image. With both Width and Height set, the image is scaled down
+// to fit in them, or cropped to fill them exactly when Crop is set; with one of them set, the image
+// is scaled down to it keeping its aspect ratio. Images are never scaled up, except to fill a crop
+type Variant struct {
+	Name   string
+	Width  int
+	Height int
+	Crop   bool
+	// Format is one of JPEG, PNG or WebP; it defaults to the format of the Processor
+	Format string
+}
+
+// DefaultVariants are the variants made when a Processor has none
+var DefaultVariants = []Variant{
+	{Name: "thumb", Width: 150, Height: 150, Crop: true},
+	{Name: "medium", Width: 800, Height: 800},
+	{Name: "large", Width: 1600, Height: 1600},
+}
+
+// Processor makes the variants of uploaded images. Images are decoded, turned upright according
+// to their EXIF orientation, resized, and encoded again without any of their metadata
+type Processor struct {
+	Variants []Variant
+	// Format is the format variants are encoded in; it defaults to the format of the image
+	Format string
+	// Quality is the quality of JPEG variants, from 1 to 100; it defaults to 85
+	Quality int
+	// StripOriginal replaces the original with an upright copy of the same size and format,
+	// without its metadata, such as the location a photo was taken at
+	StripOriginal bool
+	// MaxPixels is the largest image accepted, in pixels; it defaults to 50 million
+	MaxPixels int
+}
+
+// Image is an encoded image
+type Image struct {
+	// Name is the name of the variant, or empty for the original
+	Name        string
+	Format      string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// Ext returns the file extension of the image, including the dot
+func (i Image) Ext() string {
+	if i.Format == JPEG {
+		return ".jpg"
+	}
+	return "." + i.Format
+}
+
+// Supports reports whether images of mimeType can be processed
+func (p *Processor) Supports(mimeType string) bool {
+	_, ok := formatOf(mimeType)
+	return ok
+}
+
+// Process decodes data and returns its variants. When StripOriginal is set, the stripped original
+// comes first, with an empty name
+func (p *Processor) Process(data []byte) ([]Image, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrUnsupported, err)
+	}
+	if format != JPEG && format != PNG && format != WebP {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupported, format)
+	}
+
+	maxPixels := p.MaxPixels
+	if maxPixels <= 0 {
+		maxPixels = defaultMaxPixels
+	}
+	if config.Width*config.Height > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d", ErrTooLarge, config.Width, config.Height)
+	}
+
+	img, err := imaging.Decode(bytes.NewReader(data), imaging.AutoOrientation(true))
+	if err != nil {
+		return nil, err
+	}
+
+	var images []Image
+
+	if p.StripOriginal {
+		original, err := p.encode(img, "", format)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, original)
+	}
+
+	variants := p.Variants
+	if len(variants) == 0 {
+		variants = DefaultVariants
+	}
+
+	for _, variant := range variants {
+		variantFormat := variant.Format
+		if variantFormat == "" {
+			variantFormat = p.Format
+		}
+		if variantFormat == "" {
+			variantFormat = format
+		}
+
+		encoded, err := p.encode(Resize(img, variant), variant.Name, variantFormat)
+		if err != nil {
+			return nil, err
+		}
+		images = append(images, encoded)
+	}
+
+	return images, nil
+}
+
+// ParseVariants parses a list of variants such as "thumb:150x150:crop,medium:800x800,wide:1200x",
+// each a name, a width and height, either of which can be left out, and optionally crop and a
+// format. "default" stands for DefaultVariants
+func ParseVariants(s string) ([]Variant, error) {
+	if strings.TrimSpace(s) == "default" {
+		return DefaultVariants, nil
+	}
+
+	var variants []Variant
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+
+		fields := strings.Split(item, ":")
+		if len(fields) < 2 || fields[0] == "" {
+			return nil, fmt.Errorf("images: variant %q must be name:WIDTHxHEIGHT", item)
+		}
+		width, height, ok := strings.Cut(fields[1], "x")
+		if !ok {
+			return nil, fmt.Errorf("images: variant %q must be name:WIDTHxHEIGHT", item)
+		}
+
+		variant := Variant{Name: fields[0]}
+		var err error
+		if variant.Width, err = parseSize(width); err != nil {
+			return nil, fmt.Errorf("images: variant %q has an invalid width: %w", item, err)
+		}
+		if variant.Height, err = parseSize(height); err != nil {
+			return nil, fmt.Errorf("images: variant %q has an invalid height: %w", item, err)
+		}
+		if variant.Width == 0 && variant.Height == 0 {
+			return nil, fmt.Errorf("images: variant %q needs a width or a height", item)
+		}
+
+		for _, option := range fields[2:] {
+			switch option = strings.ToLower(option); option {
+			case "crop":
+				variant.Crop = true
+			case JPEG, PNG, WebP:
+				variant.Format = option
+			default:
+				return nil, fmt.Errorf("images: variant %q has an unknown option %q", item, option)
+			}
+		}
+		variants = append(variants, variant)
+	}
+	return variants, nil
+}
+
+// parseSize parses a width or height, where an empty one is 0
+func parseSize(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	size, err := strconv.Atoi(s)
+	if err == nil && size < 0 {
+		err = errors.New("size is negative")
+	}
+	return size, err
+}
+
+// Resize returns img resized as described by variant
+func Resize(img image.Image, variant Variant) image.Image {
+	bounds := img.Bounds()
+
+	switch {
+	case variant.Width > 0 && variant.Height > 0 && variant.Crop:
+		return imaging.Fill(img, variant.Width, variant.Height, imaging.Center, imaging.Lanczos)
+	case variant.Width > 0 && variant.Height > 0:
+		return imaging.Fit(img, variant.Width, variant.Height, imaging.Lanczos)
+	case variant.Width > 0 && bounds.Dx() > variant.Width:
+		return imaging.Resize(img, variant.Width, 0, imaging.Lanczos)
+	case variant.Height > 0 && bounds.Dy() > variant.Height:
+		return imaging.Resize(img, 0, variant.Height, imaging.Lanczos)
+	}
+	return img
+}
+
+func (p *Processor) encode(img image.Image, name, format string) (Image, error) {
+	var buf bytes.Buffer
+	var err error
+
+	switch format {
+	case JPEG:
+		quality := p.Quality
+		if quality <= 0 || quality > 100 {
+			quality = 85
+		}
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	case PNG:
+		err = png.Encode(&buf, img)
+	case WebP:
+		// the encoder is lossless, so WebP variants are larger than JPEG ones
+		err = nativewebp.Encode(&buf, img, nil)
+	default:
+		err = fmt.Errorf("%w: %s", ErrUnsupported, format)
+	}
+	if err != nil {
+		return Image{}, err
+	}
+
+	return Image{
+		Name:        name,
+		Format:      format,
+		ContentType: "image/" + format,
+		Width:       img.Bounds().Dx(),
+		Height:      img.Bounds().Dy(),
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+func formatOf(mimeType string) (string, bool) {
+	switch mimeType {
+	case "image/jpeg", "image/pjpeg":
+		return JPEG, true
+	case "image/png":
+		return PNG, true
+	case "image/webp":
+		return WebP, true
+	}
+	return "", false
+}
@@ -0,0 +1,233 @@
+package images
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+// testImage returns an image that is red on its left half and blue on its right one
+func testImage(width, height int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			c := color.RGBA{R: 255, A: 255}
+			if x >= width/2 {
+				c = color.RGBA{B: 255, A: 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	return img
+}
+
+func encodePNG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// encodeJPEG encodes img with an EXIF segment saying it has to be rotated by 90 degrees clockwise
+func encodeJPEG(t *testing.T, img image.Image) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tiff := []byte{
+		'M', 'M', 0, 42, 0, 0, 0, 8, // header, first IFD at offset 8
+		0, 1, // one entry
+		0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, 6, 0, 0, // orientation, SHORT, 1 value: 6
+		0, 0, 0, 0, // no next IFD
+	}
+	payload := append([]byte("Exif\x00\x00"), tiff...)
+	segment := append([]byte{0xFF, 0xE1, byte((len(payload) + 2) >> 8), byte(len(payload) + 2)}, payload...)
+
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestProcessor_Variants(t *testing.T) {
+	p := &Processor{}
+	processed, err := p.Process(encodePNG(t, testImage(2000, 1000)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		width  int
+		height int
+	}{
+		{"thumb", 150, 150},
+		{"medium", 800, 400},
+		{"large", 1600, 800},
+	}
+
+	if len(processed) != len(tests) {
+		t.Fatalf("expected %d images, got %d", len(tests), len(processed))
+	}
+
+	for i, e := range tests {
+		img := processed[i]
+		if img.Name != e.name {
+			t.Errorf("%d: expected %s, got %s", i, e.name, img.Name)
+		}
+		if img.Format != PNG || img.ContentType != "image/png" || img.Ext() != ".png" {
+			t.Errorf("%s: expected a png, got %s %s", e.name, img.Format, img.ContentType)
+		}
+
+		decoded, err := png.Decode(bytes.NewReader(img.Data))
+		if err != nil {
+			t.Fatalf("%s: %s", e.name, err)
+		}
+		if decoded.Bounds().Dx() != e.width || decoded.Bounds().Dy() != e.height {
+			t.Errorf("%s: expected %dx%d, got %v", e.name, e.width, e.height, decoded.Bounds().Size())
+		}
+	}
+}
+
+func TestProcessor_Orientation(t *testing.T) {
+	p := &Processor{
+		Variants:      []Variant{{Name: "small", Width: 20}},
+		StripOriginal: true,
+	}
+	processed, err := p.Process(encodeJPEG(t, testImage(80, 40)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(processed) != 2 {
+		t.Fatalf("expected 2 images, got %d", len(processed))
+	}
+
+	original := processed[0]
+	if original.Name != "" || original.Format != JPEG || original.Ext() != ".jpg" {
+		t.Errorf("expected the original first, got %q %s", original.Name, original.Format)
+	}
+	if original.Width != 40 || original.Height != 80 {
+		t.Errorf("expected the original to be turned upright, got %dx%d", original.Width, original.Height)
+	}
+	if bytes.Contains(original.Data, []byte("Exif")) {
+		t.Error("expected the metadata of the original to be removed")
+	}
+
+	decoded, err := jpeg.Decode(bytes.NewReader(original.Data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// once rotated clockwise, the red half is on top
+	if r, _, b, _ := decoded.At(20, 10).RGBA(); r < b {
+		t.Error("expected the top of the image to be red")
+	}
+
+	if processed[1].Width != 20 || processed[1].Height != 40 {
+		t.Errorf("expected the variant to be 20x40, got %dx%d", processed[1].Width, processed[1].Height)
+	}
+}
+
+func TestProcessor_Formats(t *testing.T) {
+	p := &Processor{
+		Variants: []Variant{
+			{Name: "webp", Width: 10, Format: WebP},
+			{Name: "jpeg", Width: 10},
+		},
+		Format: JPEG,
+	}
+	processed, err := p.Process(encodePNG(t, testImage(20, 20)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if processed[0].Format != WebP || !bytes.HasPrefix(processed[0].Data, []byte("RIFF")) {
+		t.Errorf("expected a webp variant, got %s", processed[0].Format)
+	}
+	if _, _, err := image.Decode(bytes.NewReader(processed[0].Data)); err != nil {
+		t.Errorf("expected the webp variant to decode, got %s", err)
+	}
+	if processed[1].Format != JPEG {
+		t.Errorf("expected the format of the processor, got %s", processed[1].Format)
+	}
+}
+
+func TestProcessor_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Processor
+		data []byte
+		err  error
+	}{
+		{"not an image", &Processor{}, []byte("hello"), ErrUnsupported},
+		{"too large", &Processor{MaxPixels: 100}, encodePNG(t, testImage(20, 20)), ErrTooLarge},
+		{"unknown format", &Processor{Format: "bmp"}, encodePNG(t, testImage(20, 20)), ErrUnsupported},
+	}
+
+	for _, e := range tests {
+		_, err := e.p.Process(e.data)
+		if !errors.Is(err, e.err) {
+			t.Errorf("%s: expected %v, got %v", e.name, e.err, err)
+		}
+	}
+}
+
+func TestProcessor_Supports(t *testing.T) {
+	p := &Processor{}
+	for mimeType, expected := range map[string]bool{
+		"image/jpeg": true,
+		"image/png":  true,
+		"image/webp": true,
+		"image/gif":  false,
+		"text/plain": false,
+	} {
+		if p.Supports(mimeType) != expected {
+			t.Errorf("%s: expected %v", mimeType, expected)
+		}
+	}
+}
+
+func TestParseVariants(t *testing.T) {
+	tests := []struct {
+		name     string
+		s        string
+		expected []Variant
+		err      bool
+	}{
+		{"empty", "", nil, false},
+		{"default", "default", DefaultVariants, false},
+		{"variants", "thumb:150x150:crop, medium:800x800:webp,wide:1200x,tall:x900", []Variant{
+			{Name: "thumb", Width: 150, Height: 150, Crop: true},
+			{Name: "medium", Width: 800, Height: 800, Format: WebP},
+			{Name: "wide", Width: 1200},
+			{Name: "tall", Height: 900},
+		}, false},
+		{"no size", "thumb", nil, true},
+		{"no separator", "thumb:150", nil, true},
+		{"no width or height", "thumb:x", nil, true},
+		{"bad width", "thumb:axb", nil, true},
+		{"negative", "thumb:-1x10", nil, true},
+		{"unknown option", "thumb:10x10:gif", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, err := ParseVariants(tt.s)
+			if (err != nil) != tt.err {
+				t.Fatalf("expected an error: %v, got %v", tt.err, err)
+			}
+			if len(variants) != len(tt.expected) {
+				t.Fatalf("expected %+v, got %+v", tt.expected, variants)
+			}
+			for i := range variants {
+				if variants[i] != tt.expected[i] {
+					t.Errorf("expected %+v, got %+v", tt.expected[i], variants[i])
+				}
+			}
+		})
+	}
+}
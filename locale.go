@@ -0,0 +1,108 @@
+package velox
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/FernandoJVideira/velox/i18n"
+)
+
+// localeKey is the name of the cookie and session key holding the user's chosen locale
+const localeKey = "locale"
+
+// DetectLocale works out the locale of each request and stores it in the request context.
+// It looks, in order, at a locale prefix in the url (/pt/about), the locale cookie, the
+// session and the Accept-Language header, and falls back to DEFAULT_LOCALE. Only the prefixes
+// listed in LOCALE_URL_PREFIXES are read, so urls such as /id/5 keep working once a locale of
+// the same name is loaded. The prefix is stripped from the url of the request passed on, so
+// routes don't need to be declared once per locale
+func (v *Velox) DetectLocale(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if v.Translator == nil || len(v.Translator.Locales()) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		locale := ""
+
+		var stripped *url.URL
+		segments := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)
+		if v.isLocalePrefix(segments[0]) {
+			locale = v.Translator.Match(segments[0])
+			if locale != "" {
+				u := *r.URL
+				u.Path = "/"
+				if len(segments) == 2 {
+					u.Path += segments[1]
+				}
+				u.RawPath = ""
+				stripped = &u
+			}
+		}
+
+		if locale == "" {
+			if cookie, err := r.Cookie(localeKey); err == nil {
+				locale = v.Translator.Match(cookie.Value)
+			}
+		}
+
+		if locale == "" && v.Session != nil {
+			locale = v.Translator.Match(v.Session.GetString(r.Context(), localeKey))
+		}
+
+		if locale == "" {
+			locale = v.Translator.Match(i18n.ParseAcceptLanguage(r.Header.Get("Accept-Language"))...)
+		}
+
+		if locale == "" {
+			locale = v.Translator.DefaultLocale
+		}
+
+		w.Header().Add("Vary", "Accept-Language")
+		r = r.WithContext(i18n.WithLocale(r.Context(), locale))
+		if stripped != nil {
+			r.URL = stripped
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// isLocalePrefix reports whether segment is one of the url prefixes in LOCALE_URL_PREFIXES
+func (v *Velox) isLocalePrefix(segment string) bool {
+	if segment == "" {
+		return false
+	}
+	for _, prefix := range v.config.localePrefixes {
+		if strings.EqualFold(prefix, segment) {
+			return true
+		}
+	}
+	return false
+}
+
+// Locale returns the locale of the request, as detected by DetectLocale
+func (v *Velox) Locale(r *http.Request) string {
+	if locale := i18n.LocaleFromContext(r.Context()); locale != "" {
+		return locale
+	}
+	if v.Translator != nil {
+		return v.Translator.DefaultLocale
+	}
+	return ""
+}
+
+// SetLocale remembers the locale the user chose, in the session and in a cookie
+func (v *Velox) SetLocale(w http.ResponseWriter, r *http.Request, locale string) {
+	v.Session.Put(r.Context(), localeKey, locale)
+	http.SetCookie(w, v.NewCookie(localeKey, locale, time.Now().Add(365*24*time.Hour)))
+}
+
+// T translates key into the locale of the request
+func (v *Velox) T(r *http.Request, key string, args ...interface{}) string {
+	if v.Translator == nil {
+		return key
+	}
+	return v.Translator.T(v.Locale(r), key, args...)
+}
@@ -0,0 +1,61 @@
+package velox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/FernandoJVideira/velox/i18n"
+)
+
+func TestDetectLocale(t *testing.T) {
+	translator := i18n.New("en")
+	for _, locale := range []string{"en", "pt", "id"} {
+		translator.Add(locale, map[string]interface{}{"greeting": locale})
+	}
+
+	tests := []struct {
+		name     string
+		prefixes []string
+		path     string
+		cookie   string
+		locale   string
+		seenPath string
+	}{
+		{"prefix", []string{"pt"}, "/pt/about", "", "pt", "/about"},
+		{"prefix only", []string{"pt"}, "/pt", "", "pt", "/"},
+		{"prefix case", []string{"pt"}, "/PT/about", "", "pt", "/about"},
+		{"loaded locale not a prefix", []string{"pt"}, "/id/5", "", "en", "/id/5"},
+		{"prefixes off", nil, "/pt/about", "", "en", "/pt/about"},
+		{"prefix without translations", []string{"fr"}, "/fr/about", "", "en", "/fr/about"},
+		{"cookie", []string{"pt"}, "/id/5", "id", "id", "/id/5"},
+		{"prefix wins over cookie", []string{"pt"}, "/pt/about", "id", "pt", "/about"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := newTestVelox()
+			v.Translator = translator
+			v.config.localePrefixes = tt.prefixes
+
+			var locale, seenPath string
+			handler := v.DetectLocale(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				locale = v.Locale(r)
+				seenPath = r.URL.Path
+			}))
+
+			r := httptest.NewRequest("GET", tt.path, nil)
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: localeKey, Value: tt.cookie})
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), r)
+
+			if locale != tt.locale || seenPath != tt.seenPath {
+				t.Errorf("expected %s at %s, got %s at %s", tt.locale, tt.seenPath, locale, seenPath)
+			}
+			if r.URL.Path != tt.path {
+				t.Errorf("the url of the original request was changed to %s", r.URL.Path)
+			}
+		})
+	}
+}
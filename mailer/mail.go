@@ -2,7 +2,11 @@ package mailer
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"github.com/CloudyKit/jet/v6"
+	"github.com/FernandoJVideira/velox/i18n"
+	"github.com/FernandoJVideira/velox/render"
 	apimail "github.com/ainsleyclark/go-mail"
 	"github.com/vanng822/go-premailer/premailer"
 	mail "github.com/xhit/go-simple-mail/v2"
@@ -27,6 +31,14 @@ type Mail struct {
 	API         string
 	APIKey      string
 	APIUrl      string
+	Renderer    Renderer
+	Translator  *i18n.Translator
+}
+
+// Renderer renders a view to a string. The velox renderer satisfies it, so the
+// application's views can be used as the html body of an email
+type Renderer interface {
+	String(view string, variables, data interface{}) (string, error)
 }
 
 type Message struct {
@@ -35,6 +47,8 @@ type Message struct {
 	To          string
 	Subject     string
 	Template    string
+	View        string
+	Locale      string
 	Attachments []string
 	Data        interface{}
 }
@@ -178,7 +192,9 @@ func (m *Mail) SendSMTPMessage(msg Message) error {
 		SetSubject(msg.Subject)
 
 	email.SetBody(mail.TextHTML, formattedMessage)
-	email.AddAlternative(mail.TextPlain, plainMessage)
+	if plainMessage != "" {
+		email.AddAlternative(mail.TextPlain, plainMessage)
+	}
 
 	if len(msg.Attachments) > 0 {
 		for _, attachment := range msg.Attachments {
@@ -208,9 +224,13 @@ func (m *Mail) getEncryption(encryption string) mail.Encryption {
 }
 
 func (m *Mail) buildHTMLMessage(msg Message) (string, error) {
+	if msg.View != "" {
+		return m.buildViewMessage(msg)
+	}
+
 	templateToRender := fmt.Sprintf("%s/%s.html.tmpl", m.Templates, msg.Template)
 
-	t, err := template.New("email-html").ParseFiles(templateToRender)
+	t, err := template.New("email-html").Funcs(m.templateFuncs(msg)).ParseFiles(templateToRender)
 	if err != nil {
 		return "", err
 	}
@@ -231,10 +251,75 @@ func (m *Mail) buildHTMLMessage(msg Message) (string, error) {
 	return formattedMessage, nil
 }
 
+// buildViewMessage renders the html body from one of the application's views
+func (m *Mail) buildViewMessage(msg Message) (string, error) {
+	if m.Renderer == nil {
+		return "", errors.New("no renderer set to render the mail view")
+	}
+
+	variables, data := m.viewData(msg)
+	formattedMessage, err := m.Renderer.String(msg.View, variables, data)
+	if err != nil {
+		return "", err
+	}
+
+	formattedMessage, err = m.inlineCSS(formattedMessage)
+	if err != nil {
+		return "", err
+	}
+
+	return formattedMessage, nil
+}
+
+// viewData returns the variables and data a view is rendered with, so T translates into the locale
+// of the message: template data gets the locale, and jet views of other data get a T variable
+func (m *Mail) viewData(msg Message) (jet.VarMap, interface{}) {
+	if msg.Locale == "" {
+		return nil, msg.Data
+	}
+
+	switch td := msg.Data.(type) {
+	case nil:
+		return nil, &render.TemplateData{Locale: msg.Locale}
+	case *render.TemplateData:
+		localized := *td
+		if localized.Locale == "" {
+			localized.Locale = msg.Locale
+		}
+		return nil, &localized
+	default:
+		variables := make(jet.VarMap)
+		variables.Set("T", m.templateFuncs(msg)["T"])
+		return variables, msg.Data
+	}
+}
+
+// templateFuncs returns the functions available to mail templates. T translates a key
+// into the locale of the message
+func (m *Mail) templateFuncs(msg Message) template.FuncMap {
+	return template.FuncMap{
+		"T": func(key string, args ...interface{}) string {
+			if m.Translator == nil {
+				return key
+			}
+			locale := msg.Locale
+			if locale == "" {
+				locale = m.Translator.DefaultLocale
+			}
+			return m.Translator.T(locale, key, args...)
+		},
+	}
+}
+
 func (m *Mail) buildPlainTextMessage(msg Message) (string, error) {
+	// messages rendered from a view may be html only
+	if msg.Template == "" && msg.View != "" {
+		return "", nil
+	}
+
 	templateToRender := fmt.Sprintf("%s/%s.plain.tmpl", m.Templates, msg.Template)
 
-	t, err := template.New("email-html").ParseFiles(templateToRender)
+	t, err := template.New("email-html").Funcs(m.templateFuncs(msg)).ParseFiles(templateToRender)
 	if err != nil {
 		return "", err
 	}
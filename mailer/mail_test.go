@@ -1,6 +1,13 @@
 package mailer
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/FernandoJVideira/velox/i18n"
+	"github.com/FernandoJVideira/velox/render"
+)
 
 func TestMail_SendSMTPMessage(t *testing.T) {
 	msg := Message{
@@ -148,3 +155,98 @@ func TestMail_ChooseAPI(t *testing.T) {
 	mailer.APIKey = ""
 	mailer.APIUrl = ""
 }
+
+type testRenderer struct{}
+
+func (testRenderer) String(view string, variables, data interface{}) (string, error) {
+	return "<p>" + view + "</p>", nil
+}
+
+// localeRenderer renders the locale of the data, or T's translation of hello
+type localeRenderer struct{}
+
+func (localeRenderer) String(view string, variables, data interface{}) (string, error) {
+	if td, ok := data.(*render.TemplateData); ok {
+		return "<p>" + td.Locale + "</p>", nil
+	}
+	translate := variables.(jet.VarMap)["T"].Interface().(func(string, ...interface{}) string)
+	return "<p>" + translate("hello") + "</p>", nil
+}
+
+func TestMail_BuildViewMessage(t *testing.T) {
+	m := Mail{}
+	msg := Message{View: "welcome"}
+
+	_, err := m.buildHTMLMessage(msg)
+	if err == nil {
+		t.Error("expected error building a view message without a renderer")
+	}
+
+	m.Renderer = testRenderer{}
+	html, err := m.buildHTMLMessage(msg)
+	if err != nil {
+		t.Error(err)
+	}
+	if !strings.Contains(html, "<p>welcome</p>") {
+		t.Error("view was not rendered into the message:", html)
+	}
+
+	plain, err := m.buildPlainTextMessage(msg)
+	if err != nil || plain != "" {
+		t.Error("expected an empty plain text body for a view message without template")
+	}
+}
+
+func TestMail_BuildViewMessageLocale(t *testing.T) {
+	translator := i18n.New("en")
+	translator.Add("pt", map[string]interface{}{"hello": "Olá"})
+	m := Mail{Renderer: localeRenderer{}, Translator: translator}
+
+	data := &render.TemplateData{}
+	tests := []struct {
+		name     string
+		data     interface{}
+		expected string
+	}{
+		{"no data", nil, "<p>pt</p>"},
+		{"template data", data, "<p>pt</p>"},
+		{"other data", map[string]string{}, "<p>Olá</p>"},
+	}
+
+	for _, tt := range tests {
+		html, err := m.buildViewMessage(Message{View: "welcome", Locale: "pt", Data: tt.data})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(html, tt.expected) {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.expected, html)
+		}
+	}
+
+	if data.Locale != "" {
+		t.Error("expected the message's data to be left alone")
+	}
+}
+
+func TestMail_TemplateFuncs(t *testing.T) {
+	translator := i18n.New("en")
+	translator.Add("en", map[string]interface{}{"hello": "Hello"})
+	translator.Add("pt", map[string]interface{}{"hello": "Olá"})
+
+	m := Mail{}
+	translate := m.templateFuncs(Message{Locale: "pt"})["T"].(func(string, ...interface{}) string)
+	if translate("hello") != "hello" {
+		t.Error("expected the key when there is no translator")
+	}
+
+	m.Translator = translator
+	translate = m.templateFuncs(Message{Locale: "pt"})["T"].(func(string, ...interface{}) string)
+	if translate("hello") != "Olá" {
+		t.Error("message was not translated into its locale")
+	}
+
+	translate = m.templateFuncs(Message{})["T"].(func(string, ...interface{}) string)
+	if translate("hello") != "Hello" {
+		t.Error("message without locale was not translated into the default locale")
+	}
+}
@@ -1,7 +1,11 @@
 package velox
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"os"
 	"strconv"
@@ -11,24 +15,80 @@ import (
 )
 
 func (v *Velox) SessionLoad(next http.Handler) http.Handler {
-	return v.Session.LoadAndSave(next)
+	handler := v.Session.LoadAndSave(next)
+	if partitioned, _ := strconv.ParseBool(v.config.cookie.partitioned); partitioned {
+		return partitionCookies(handler)
+	}
+	return handler
 }
 
+// NoSurf checks the CSRF token of requests that change state. Multipart requests that send the
+// token in the X-CSRF-Token header, or in the csrf_token field when it is the first one, as the
+// hidden input at the top of a form is, aren't parsed for it, so their uploads can still be
+// streamed. Other multipart requests have their form parsed as usual
 func (v *Velox) NoSurf(next http.Handler) http.Handler {
 	csrfHandler := nosurf.New(next)
-	secure, _ := strconv.ParseBool(v.config.cookie.secure)
 
 	csrfHandler.ExemptGlob("/api/*")
+	csrfHandler.ExemptFunc(v.isCSRFExempt)
+
+	csrfHandler.SetBaseCookie(v.csrfCookie())
+
+	return multipartToken(csrfHandler)
+}
+
+// multipartToken moves the CSRF token of a multipart request to the X-CSRF-Token header when it is
+// the first field. nosurf only parses the form when the header is empty, which would buffer the
+// files to memory or temporary files
+func multipartToken(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mediaType, params, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "multipart/form-data" || params["boundary"] == "" || isSafeMethod(r.Method) {
+			next.ServeHTTP(w, r)
+			return
+		}
 
-	csrfHandler.SetBaseCookie(http.Cookie{
-		HttpOnly: true,
-		Path:     "/",
-		Secure:   secure,
-		SameSite: http.SameSiteStrictMode,
-		Domain:   v.config.cookie.domain,
+		if r.Header.Get(nosurf.HeaderName) == "" {
+			if token := peekToken(r, params["boundary"]); token != "" {
+				r.Header.Set(nosurf.HeaderName, token)
+			}
+		}
+		next.ServeHTTP(w, r)
 	})
+}
+
+// peekToken returns the value of the first part of a multipart body when it is the csrf_token
+// field. What is read of the body is put back in front of it, so handlers still get all of it
+func peekToken(r *http.Request, boundary string) string {
+	body := r.Body
+	read := new(bytes.Buffer)
+	defer func() {
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(read, body), body}
+	}()
 
-	return csrfHandler
+	reader := multipart.NewReader(io.TeeReader(io.LimitReader(body, 64<<10), read), boundary)
+	part, err := reader.NextPart()
+	if err != nil || part.FormName() != nosurf.FormFieldName || part.FileName() != "" {
+		return ""
+	}
+
+	token, err := io.ReadAll(io.LimitReader(part, 1024))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(token))
+}
+
+// isSafeMethod reports whether requests with method are let through without a CSRF check
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
 }
 
 func (v *Velox) CheckForMaintenanceMode(next http.Handler) http.Handler {
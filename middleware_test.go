@@ -0,0 +1,142 @@
+package velox
+
+import (
+	"bytes"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/justinas/nosurf"
+)
+
+func TestNoSurf_MultipartUploads(t *testing.T) {
+	dir := t.TempDir()
+
+	v := newTestVelox()
+	v.Session = scs.New()
+	v.config.uploads.maxUploadSize = 1 << 20
+	v.config.uploads.allowedMimeTypes = []string{"text/plain"}
+
+	mux := v.routes().(*chi.Mux)
+	mux.Get("/token", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(nosurf.Token(r)))
+	})
+	mux.Post("/upload", func(w http.ResponseWriter, r *http.Request) {
+		streamed := r.MultipartForm == nil
+		uploaded, err := v.UploadFile(r, dir, "file", nil)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+			return
+		}
+		_, _ = w.Write([]byte(strconv.FormatBool(streamed) + "|" + r.Form.Get("title") + "|" + filepath.Base(uploaded.Key)))
+	})
+	mux.Post("/form", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.PostFormValue("title")))
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/token", nil))
+	token := w.Body.String()
+	cookies := w.Result().Cookies()
+
+	multipartBody := func(fields ...string) (io.Reader, string) {
+		body := new(bytes.Buffer)
+		mw := multipart.NewWriter(body)
+		for _, field := range fields {
+			if field == "file" {
+				part, _ := mw.CreateFormFile("file", "notes.txt")
+				_, _ = part.Write(bytes.Repeat([]byte("some notes\n"), 1000))
+				continue
+			}
+			name, value, _ := strings.Cut(field, "=")
+			_ = mw.WriteField(name, value)
+		}
+		_ = mw.Close()
+		return body, mw.FormDataContentType()
+	}
+
+	tests := []struct {
+		name   string
+		path   string
+		fields []string
+		header string
+		status int
+		body   string
+	}{
+		{"token field first", "/upload", []string{"csrf_token=" + token, "title=notes", "file"}, "", http.StatusOK, "true|notes|"},
+		{"token header", "/upload", []string{"title=notes", "file"}, token, http.StatusOK, "true|notes|"},
+		{"token field after others", "/upload", []string{"title=notes", "csrf_token=" + token, "file"}, "", http.StatusOK, "false|notes|"},
+		{"wrong token", "/upload", []string{"csrf_token=nope", "file"}, "", http.StatusBadRequest, ""},
+		{"wrong token after others", "/upload", []string{"title=notes", "csrf_token=nope", "file"}, "", http.StatusBadRequest, ""},
+		{"no token", "/upload", []string{"file"}, "", http.StatusBadRequest, ""},
+		{"form values", "/form", []string{"csrf_token=" + token, "title=notes"}, "", http.StatusOK, "notes"},
+		{"form values token last", "/form", []string{"title=notes", "csrf_token=" + token}, "", http.StatusOK, "notes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, contentType := multipartBody(tt.fields...)
+			r := httptest.NewRequest("POST", tt.path, body)
+			r.Header.Set("Content-Type", contentType)
+			if tt.header != "" {
+				r.Header.Set(nosurf.HeaderName, tt.header)
+			}
+			for _, cookie := range cookies {
+				r.AddCookie(cookie)
+			}
+
+			w := httptest.NewRecorder()
+			mux.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Fatalf("expected status %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+			if !strings.HasPrefix(w.Body.String(), tt.body) {
+				t.Errorf("expected the body to start with %q, got %q", tt.body, w.Body.String())
+			}
+		})
+	}
+
+	files, _ := os.ReadDir(dir)
+	if len(files) != 3 {
+		t.Errorf("expected the three accepted uploads to be stored, got %d files", len(files))
+	}
+}
+
+func TestNoSurf_URLEncodedForm(t *testing.T) {
+	v := newTestVelox()
+	v.Session = scs.New()
+
+	mux := v.routes().(*chi.Mux)
+	mux.Get("/token", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(nosurf.Token(r)))
+	})
+	mux.Post("/form", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.PostFormValue("title")))
+	})
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/token", nil))
+	form := url.Values{"csrf_token": {w.Body.String()}, "title": {"notes"}}
+
+	r := httptest.NewRequest("POST", "/form", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range w.Result().Cookies() {
+		r.AddCookie(cookie)
+	}
+
+	w = httptest.NewRecorder()
+	mux.ServeHTTP(w, r)
+	if w.Code != http.StatusOK || w.Body.String() != "notes" {
+		t.Errorf("expected the form to pass the check, got %d %q", w.Code, w.Body.String())
+	}
+}
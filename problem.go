@@ -0,0 +1,131 @@
+package velox
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Problem is an RFC 7807 problem details document, the body of API error responses. Errors
+// holds field-level validation errors, in the same shape as Validation.Errors
+type Problem struct {
+	Type     string            `json:"type"`
+	Title    string            `json:"title"`
+	Status   int               `json:"status"`
+	Detail   string            `json:"detail,omitempty"`
+	Instance string            `json:"instance,omitempty"`
+	Errors   map[string]string `json:"errors,omitempty"`
+	err      error
+}
+
+// Error makes a problem usable as an error, so functions such as ReadJSON can return one
+func (p *Problem) Error() string {
+	if p.Detail != "" {
+		return p.Detail
+	}
+	return p.Title
+}
+
+// Unwrap returns the error the problem was made from, if any
+func (p *Problem) Unwrap() error {
+	return p.err
+}
+
+// WriteProblem writes problem as application/problem+json. A non-zero status takes precedence
+// over the status of the problem, and an empty type and title default to about:blank and the
+// status text
+func (v *Velox) WriteProblem(w http.ResponseWriter, status int, problem *Problem) error {
+	if problem == nil {
+		problem = &Problem{}
+	}
+	if status != 0 {
+		problem.Status = status
+	}
+	if problem.Status == 0 {
+		problem.Status = http.StatusInternalServerError
+	}
+	if problem.Type == "" {
+		problem.Type = "about:blank"
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(problem.Status)
+	}
+
+	out, err := json.Marshal(problem)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	_, err = w.Write(out)
+	return err
+}
+
+// Problem returns a 422 Unprocessable Entity problem holding the errors of the validation
+func (v *Validation) Problem() *Problem {
+	return &Problem{
+		Title:  http.StatusText(http.StatusUnprocessableEntity),
+		Status: http.StatusUnprocessableEntity,
+		Detail: v.message("validation.failed", "The given data was invalid"),
+		Errors: v.Errors,
+	}
+}
+
+// newProblem returns a problem for status that wraps err
+func newProblem(status int, err error, detail string) *Problem {
+	return &Problem{
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		err:    err,
+	}
+}
+
+// jsonProblem turns an error from decoding a JSON body into a problem a client can act on
+func jsonProblem(err error) *Problem {
+	var syntaxError *json.SyntaxError
+	var typeError *json.UnmarshalTypeError
+	var maxBytesError *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &syntaxError):
+		return newProblem(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrJSONSyntax, err),
+			fmt.Sprintf("Body contains badly-formed JSON (at character %d)", syntaxError.Offset))
+
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, gzip.ErrHeader), errors.Is(err, gzip.ErrChecksum):
+		return newProblem(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrJSONSyntax, err), "Body contains badly-formed JSON")
+
+	case errors.As(err, &typeError):
+		problem := newProblem(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrJSONType, err),
+			fmt.Sprintf("Body contains an incorrect JSON type (at character %d)", typeError.Offset))
+		if typeError.Field != "" {
+			problem.Detail = fmt.Sprintf("Body contains an incorrect JSON type for field %q", typeError.Field)
+			problem.Errors = map[string]string{
+				typeError.Field: fmt.Sprintf("Must be of type %s", typeError.Type),
+			}
+		}
+		return problem
+
+	case errors.Is(err, io.EOF):
+		return newProblem(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrJSONEmpty, err), "Body must not be empty")
+
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		problem := newProblem(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrJSONUnknownField, err),
+			fmt.Sprintf("Body contains unknown field %q", field))
+		problem.Errors = map[string]string{field: "Unknown field"}
+		return problem
+
+	case errors.As(err, &maxBytesError):
+		return newProblem(http.StatusRequestEntityTooLarge, fmt.Errorf("%w: %w", ErrJSONTooLarge, err),
+			fmt.Sprintf("Body must not be larger than %d bytes", maxBytesError.Limit))
+
+	default:
+		return newProblem(http.StatusBadRequest, fmt.Errorf("%w: %w", ErrJSONSyntax, err), "Body could not be decoded")
+	}
+}
@@ -0,0 +1,88 @@
+package velox
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestReadJSON_Problems(t *testing.T) {
+	type person struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		detail string
+		errors map[string]string
+		err    error
+	}{
+		{"syntax error", `{"name": }`, http.StatusBadRequest,
+			"Body contains badly-formed JSON (at character 10)", nil, ErrJSONSyntax},
+		{"unexpected end", `{"name": "ana"`, http.StatusBadRequest,
+			"Body contains badly-formed JSON", nil, ErrJSONSyntax},
+		{"type mismatch", `{"age": "ten"}`, http.StatusBadRequest,
+			`Body contains an incorrect JSON type for field "age"`, map[string]string{"age": "Must be of type int"}, ErrJSONType},
+		{"type mismatch at the top", `["ana"]`, http.StatusBadRequest,
+			"Body contains an incorrect JSON type (at character 1)", nil, ErrJSONType},
+		{"unknown field", `{"name": "ana", "nick": "an"}`, http.StatusBadRequest,
+			`Body contains unknown field "nick"`, map[string]string{"nick": "Unknown field"}, ErrJSONUnknownField},
+		{"too large", `{"name": "` + strings.Repeat("a", 64) + `"}`, http.StatusRequestEntityTooLarge,
+			"Body must not be larger than 32 bytes", nil, ErrJSONTooLarge},
+		{"empty", ``, http.StatusBadRequest,
+			"Body must not be empty", nil, ErrJSONEmpty},
+		{"several values", `{"name": "ana"}{"name": "bo"}`, http.StatusBadRequest,
+			"Body must only contain a single JSON value", nil, ErrJSONMultipleValues},
+	}
+
+	v := newTestVelox()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			err := v.ReadJSON(w, r, &person{}, JSONOptions{MaxBytes: 32, DisallowUnknownFields: true})
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+
+			var problem *Problem
+			if !errors.As(err, &problem) {
+				t.Fatalf("expected a problem, got %v", err)
+			}
+			if err := v.WriteProblem(w, 0, problem); err != nil {
+				t.Fatal(err)
+			}
+
+			var sent Problem
+			if err := json.Unmarshal(w.Body.Bytes(), &sent); err != nil {
+				t.Fatal(err)
+			}
+			if w.Code != tt.status || sent.Status != tt.status {
+				t.Errorf("expected status %d, got %d (%d in the body)", tt.status, w.Code, sent.Status)
+			}
+			if w.Header().Get("Content-Type") != "application/problem+json" {
+				t.Errorf("expected a problem document, got %s", w.Header().Get("Content-Type"))
+			}
+			if sent.Type != "about:blank" || sent.Title != http.StatusText(tt.status) {
+				t.Errorf("expected the type and title of the status, got %q %q", sent.Type, sent.Title)
+			}
+			if sent.Detail != tt.detail {
+				t.Errorf("expected detail %q, got %q", tt.detail, sent.Detail)
+			}
+			if len(sent.Errors) != len(tt.errors) {
+				t.Errorf("expected errors %v, got %v", tt.errors, sent.Errors)
+			}
+			for field, message := range tt.errors {
+				if sent.Errors[field] != message {
+					t.Errorf("%s: expected %q, got %q", field, message, sent.Errors[field])
+				}
+			}
+		})
+	}
+}
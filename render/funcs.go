@@ -0,0 +1,181 @@
+package render
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/gertd/go-pluralize"
+)
+
+var routeParam = regexp.MustCompile(`\{[^}]+\}`)
+
+var pluralizer = pluralize.NewClient()
+
+// jetHTML is markup that Jet writes to the page without escaping it
+type jetHTML string
+
+func (h jetHTML) Render(r *jet.Runtime) {
+	_, _ = r.Writer.Write([]byte(h))
+}
+
+// AddFunc registers a function that can be called from both Jet and Go templates
+func (v *Render) AddFunc(name string, fn interface{}) {
+	if v.JetViews != nil {
+		v.JetViews.AddGlobal(name, fn)
+	}
+	v.AddGoFuncs(template.FuncMap{name: fn})
+}
+
+// AddGlobal registers a value available to every template. Jet templates use it as a
+// variable, while Go templates call it as a function with no arguments
+func (v *Render) AddGlobal(name string, value interface{}) {
+	if v.JetViews != nil {
+		v.JetViews.AddGlobal(name, value)
+	}
+	v.AddGoFuncs(template.FuncMap{name: func() interface{} { return value }})
+}
+
+// AddBuiltins registers the template functions that ship with velox:
+//
+//	appURL(path)                   absolute url for a path in the application
+//	routePath(pattern, params...)  fills in the {params} of a chi route pattern, e.g. /users/{id}
+//	                               (chi routes have no names, so the pattern is given)
+//	asset(path)                    url of a file in public/, versioned by its modification time
+//	csrfField(.)                   hidden input holding the CSRF token
+//	formatDate(time, layout)       formats a time; layout defaults to 2006-01-02
+//	old(., field, default)         value submitted for a form field
+//	hasError(., field)             whether a form field failed validation
+//	fieldError(., field)           validation error of a form field
+//	pluralize(word, count)         singular or plural form of word depending on count
+func (v *Render) AddBuiltins() {
+	v.AddFunc("appURL", v.appURL)
+	v.AddFunc("routePath", routePath)
+	v.AddFunc("asset", v.asset)
+	v.AddFunc("formatDate", formatDate)
+	v.AddFunc("old", old)
+	v.AddFunc("hasError", hasError)
+	v.AddFunc("fieldError", fieldError)
+	v.AddFunc("pluralize", plural)
+
+	// csrfField must not be escaped, and each engine has its own way of marking safe markup
+	if v.JetViews != nil {
+		v.JetViews.AddGlobal("csrfField", func(td *TemplateData) jetHTML {
+			return jetHTML(csrfField(td))
+		})
+	}
+	v.AddGoFuncs(template.FuncMap{
+		"csrfField": func(td *TemplateData) template.HTML {
+			return template.HTML(csrfField(td))
+		},
+	})
+}
+
+// appURL returns the absolute url of path in the application. It isn't named url, which is
+// Jet's built-in for escaping query strings
+func (v *Render) appURL(path string) string {
+	return strings.TrimRight(v.URL, "/") + "/" + strings.TrimLeft(path, "/")
+}
+
+// asset returns the url of a file in the public folder, with a version query string that
+// changes whenever the file does, so browsers never use a stale cached copy
+func (v *Render) asset(path string) string {
+	path = strings.TrimLeft(path, "/")
+	assetURL := "/public/" + path
+
+	v.assetMu.Lock()
+	defer v.assetMu.Unlock()
+
+	version, ok := v.assetVersions[path]
+	if !ok || v.Debug {
+		info, err := os.Stat(fmt.Sprintf("%s/public/%s", v.RootPath, path))
+		if err != nil {
+			return assetURL
+		}
+		version = strconv.FormatInt(info.ModTime().Unix(), 36)
+
+		if v.assetVersions == nil {
+			v.assetVersions = make(map[string]string)
+		}
+		v.assetVersions[path] = version
+	}
+
+	return assetURL + "?v=" + version
+}
+
+// routePath replaces the {placeholders} of a chi route pattern, in order, with params
+func routePath(pattern string, params ...interface{}) string {
+	i := 0
+	return routeParam.ReplaceAllStringFunc(pattern, func(placeholder string) string {
+		if i >= len(params) {
+			return placeholder
+		}
+		value := url.PathEscape(fmt.Sprint(params[i]))
+		i++
+		return value
+	})
+}
+
+func csrfField(td *TemplateData) string {
+	if td == nil {
+		return ""
+	}
+	return fmt.Sprintf(`<input type="hidden" name="csrf_token" value="%s">`, html.EscapeString(td.CSRFToken))
+}
+
+// formatDate formats t using layout, or as 2006-01-02 when no layout is given
+func formatDate(t time.Time, layout ...string) string {
+	if t.IsZero() {
+		return ""
+	}
+	if len(layout) > 0 && layout[0] != "" {
+		return t.Format(layout[0])
+	}
+	return t.Format("2006-01-02")
+}
+
+// old returns the value submitted for field, either flashed after a failed validation or in the
+// form of the request, or def when nothing was submitted
+func old(td *TemplateData, field string, def ...string) string {
+	if td != nil {
+		if value, ok := td.Old[field]; ok {
+			return value
+		}
+		if values, ok := td.Form[field]; ok && len(values) > 0 {
+			return values[0]
+		}
+	}
+	if len(def) > 0 {
+		return def[0]
+	}
+	return ""
+}
+
+// hasError reports whether field failed validation
+func hasError(td *TemplateData, field string) bool {
+	if td == nil {
+		return false
+	}
+	_, ok := td.Errors[field]
+	return ok
+}
+
+// fieldError returns the validation error of field, or an empty string
+func fieldError(td *TemplateData, field string) string {
+	if td == nil {
+		return ""
+	}
+	return td.Errors[field]
+}
+
+// plural returns the singular or plural form of word, depending on count
+func plural(word string, count int) string {
+	return pluralizer.Pluralize(word, count, false)
+}
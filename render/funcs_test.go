@@ -0,0 +1,115 @@
+package render
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender_Builtins(t *testing.T) {
+	testRenderer.RootPath = "./testdata"
+	testRenderer.URL = "https://example.com/"
+	testRenderer.AddBuiltins()
+	testRenderer.AddFunc("shout", strings.ToUpper)
+	testRenderer.AddGlobal("appName", "velox")
+
+	expected := []string{
+		"https://example.com/users",
+		"/users/7/posts/hello%20world",
+		`<input type="hidden" name="csrf_token" value="">`,
+		"me@here.com",
+		"apples",
+		"velox",
+		"HI",
+	}
+
+	for _, renderer := range []string{"jet", "go"} {
+		r, err := http.NewRequest("POST", "/some-url", strings.NewReader("email=me@here.com"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		_ = r.ParseForm()
+
+		w := httptest.NewRecorder()
+		testRenderer.Renderer = renderer
+		err = testRenderer.Page(w, r, "funcs", nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %s", renderer, err)
+		}
+
+		body := w.Body.String()
+		for _, e := range expected {
+			if !strings.Contains(body, e) {
+				t.Errorf("%s: expected %q in %q", renderer, e, body)
+			}
+		}
+		// the builtins must not replace those of Jet, such as url
+		if renderer == "jet" && !strings.HasSuffix(strings.TrimSpace(body), "|a+b%26c") {
+			t.Errorf("expected Jet's url to escape query strings, got %q", body)
+		}
+	}
+}
+
+func TestRender_Asset(t *testing.T) {
+	testRenderer.RootPath = "./testdata"
+
+	asset := testRenderer.asset("/app.css")
+	if !strings.HasPrefix(asset, "/public/app.css?v=") {
+		t.Error("asset was not versioned:", asset)
+	}
+
+	if missing := testRenderer.asset("missing.css"); missing != "/public/missing.css" {
+		t.Error("missing asset should not be versioned:", missing)
+	}
+}
+
+func TestRender_FormatDate(t *testing.T) {
+	d := time.Date(2024, 3, 9, 10, 30, 0, 0, time.UTC)
+
+	if got := formatDate(d); got != "2024-03-09" {
+		t.Error("wrong default format:", got)
+	}
+	if got := formatDate(d, "02/01/2006 15:04"); got != "09/03/2024 10:30" {
+		t.Error("wrong custom format:", got)
+	}
+	if got := formatDate(time.Time{}); got != "" {
+		t.Error("zero time should be empty:", got)
+	}
+}
+
+func TestRender_Old(t *testing.T) {
+	if got := old(nil, "email", "default"); got != "default" {
+		t.Error("expected default value, got", got)
+	}
+	if got := old(&TemplateData{}, "email"); got != "" {
+		t.Error("expected empty value, got", got)
+	}
+
+	td := &TemplateData{
+		Old:  map[string]string{"email": "flashed@here.com"},
+		Form: map[string][]string{"email": {"submitted@here.com"}, "name": {"Ann"}},
+	}
+	if got := old(td, "email"); got != "flashed@here.com" {
+		t.Error("expected flashed value to win, got", got)
+	}
+	if got := old(td, "name"); got != "Ann" {
+		t.Error("expected submitted value, got", got)
+	}
+}
+
+func TestRender_FieldErrors(t *testing.T) {
+	td := &TemplateData{Errors: map[string]string{"email": "Invalid email address"}}
+
+	if !hasError(td, "email") || hasError(td, "name") || hasError(nil, "email") {
+		t.Error("hasError reported the wrong fields")
+	}
+	if got := fieldError(td, "email"); got != "Invalid email address" {
+		t.Error("wrong error for email:", got)
+	}
+	if got := fieldError(td, "name"); got != "" {
+		t.Error("expected no error for name, got", got)
+	}
+}
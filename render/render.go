@@ -1,13 +1,19 @@
 package render
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"log"
 	"net/http"
+	"net/url"
+	"path/filepath"
 	"strings"
+	"sync"
 
+	"github.com/FernandoJVideira/velox/i18n"
 	"github.com/justinas/nosurf"
 
 	"github.com/CloudyKit/jet/v6"
@@ -20,8 +26,17 @@ type Render struct {
 	Secure     bool
 	Port       string
 	ServerName string
+	URL        string
+	Debug      bool
 	JetViews   *jet.Set
 	Session    *scs.SessionManager
+	Translator *i18n.Translator
+	FuncMap    template.FuncMap
+
+	templateCache map[string]*template.Template
+	cacheMu       sync.RWMutex
+	assetVersions map[string]string
+	assetMu       sync.Mutex
 }
 
 type TemplateData struct {
@@ -36,18 +51,56 @@ type TemplateData struct {
 	Secure          bool
 	Error           string
 	Flash           string
+	Form            url.Values
+	Old             map[string]string
+	Errors          map[string]string
+	Locale          string
+	translator      *i18n.Translator
+}
+
+// T translates key into the locale of the page, e.g. {{ .T("welcome", "name", .Name) }} in Jet
+// or {{ .T "welcome" "name" .Name }} in Go templates
+func (td *TemplateData) T(key string, args ...interface{}) string {
+	if td.translator == nil {
+		return key
+	}
+	return td.translator.T(td.Locale, key, args...)
 }
 
+// DefaultData adds the data every template gets to td. The request may be nil, when rendering
+// outside of a request, in which case only the server settings are filled in
 func (v *Render) DefaultData(td *TemplateData, r *http.Request) *TemplateData {
 	td.Secure = v.Secure
 	td.ServerName = v.ServerName
-	td.CSRFToken = nosurf.Token(r)
 	td.Port = v.Port
+	td.translator = v.Translator
+	if td.Locale == "" && v.Translator != nil {
+		td.Locale = v.Translator.DefaultLocale
+	}
+	if r == nil {
+		return td
+	}
+	if locale := i18n.LocaleFromContext(r.Context()); locale != "" {
+		td.Locale = locale
+	}
+	td.CSRFToken = nosurf.Token(r)
+	if td.Form == nil {
+		td.Form = r.Form
+	}
+	if v.Session == nil {
+		return td
+	}
 	if v.Session.Exists(r.Context(), "userID") {
 		td.IsAuthenticated = true
 	}
 	td.Error = v.Session.PopString(r.Context(), "error")
 	td.Flash = v.Session.PopString(r.Context(), "flash")
+	if old, ok := v.Session.Pop(r.Context(), "old").(map[string]string); ok && td.Old == nil {
+		td.Old = old
+	}
+	if errs, ok := v.Session.Pop(r.Context(), "errors").(map[string]string); ok && td.Errors == nil {
+		td.Errors = errs
+	}
 	return td
 }
 
@@ -63,41 +116,129 @@ func (v *Render) Page(w http.ResponseWriter, r *http.Request, view string, varia
 	return errors.New("no renderer specified")
 }
 
-// GoPage renders a template using Go's template package
+// GoPage renders a template using Go's template package. The page is parsed together with
+// every *.layout.tmpl and *.partial.tmpl file in the views folder, and the result is cached
+// unless the renderer is in debug mode
 func (v *Render) GoPage(w http.ResponseWriter, r *http.Request, view string, data interface{}) error {
-	tmpl, err := template.ParseFiles(fmt.Sprintf("%s/views/%s.page.tmpl", v.RootPath, view))
+	return v.write(w, func(buf *bytes.Buffer) error {
+		return v.executeGo(buf, r, view, data)
+	})
+}
+
+// JetPage renders a template using Jet template engine
+func (v *Render) JetPage(w http.ResponseWriter, r *http.Request, view string, variables, data interface{}) error {
+	return v.write(w, func(buf *bytes.Buffer) error {
+		return v.executeJet(buf, r, view, variables, data)
+	})
+}
+
+// String renders a view to a string outside of a request, e.g. for email bodies or cached html.
+// Data may be a *TemplateData or any other value the template expects
+func (v *Render) String(view string, variables, data interface{}) (string, error) {
+	b, err := v.Bytes(nil, view, variables, data)
+	return string(b), err
+}
+
+// Bytes renders a view to a byte slice. When r is not nil, the template gets the same
+// default data (CSRF token, flash messages...) as a page would
+func (v *Render) Bytes(r *http.Request, view string, variables, data interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+
+	var err error
+	switch strings.ToLower(v.Renderer) {
+	case "go":
+		err = v.executeGo(buf, r, view, data)
+	case "jet":
+		err = v.executeJet(buf, r, view, variables, data)
+	default:
+		err = errors.New("no renderer specified")
+	}
 	if err != nil {
-		return err
+		return nil, err
 	}
-	td := &TemplateData{}
-	if data != nil {
-		td = data.(*TemplateData)
+
+	return buf.Bytes(), nil
+}
+
+// Partial renders a fragment of a page to a string, e.g. for htmx responses. With the go renderer
+// the view is a template from the *.partial.tmpl files, so no layout is applied; with jet it is
+// rendered like any other view. The request may be nil
+func (v *Render) Partial(r *http.Request, view string, variables, data interface{}) (string, error) {
+	buf := new(bytes.Buffer)
+
+	var err error
+	switch strings.ToLower(v.Renderer) {
+	case "go":
+		err = v.executeGoPartial(buf, r, view, data)
+	case "jet":
+		err = v.executeJet(buf, r, view, variables, data)
+	default:
+		err = errors.New("no renderer specified")
+	}
+	if err != nil {
+		return "", err
 	}
 
-	err = tmpl.Execute(w, &td)
+	return buf.String(), nil
+}
+
+// write renders into a buffer first, so a failing template doesn't send half a page
+func (v *Render) write(w http.ResponseWriter, render func(*bytes.Buffer) error) error {
+	buf := new(bytes.Buffer)
+	err := render(buf)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	_, err = buf.WriteTo(w)
+	return err
 }
 
-// JetPage renders a template using Jet template engine
-func (v *Render) JetPage(w http.ResponseWriter, r *http.Request, view string, variables, data interface{}) error {
-	var vars jet.VarMap
+// templateData returns the value a template is executed with. TemplateData gets the default data
+// added, while any other type of data is passed to the template untouched
+func (v *Render) templateData(r *http.Request, data interface{}) interface{} {
+	switch td := data.(type) {
+	case nil:
+		return v.DefaultData(&TemplateData{}, r)
+	case *TemplateData:
+		return v.DefaultData(td, r)
+	default:
+		return data
+	}
+}
 
-	if variables == nil {
-		vars = make(jet.VarMap)
-	} else {
-		vars = variables.(jet.VarMap)
+func (v *Render) executeGo(w io.Writer, r *http.Request, view string, data interface{}) error {
+	tmpl, err := v.goTemplate(view)
+	if err != nil {
+		return err
 	}
 
-	td := &TemplateData{}
-	if data != nil {
-		td = data.(*TemplateData)
+	return tmpl.Execute(w, v.templateData(r, data))
+}
+
+func (v *Render) executeGoPartial(w io.Writer, r *http.Request, view string, data interface{}) error {
+	tmpl, err := v.goTemplate("")
+	if err != nil {
+		return err
 	}
 
-	td = v.DefaultData(td, r)
+	// partials can be referred to by the name they define or by file name
+	name := view
+	if tmpl.Lookup(name) == nil {
+		name = view + ".partial.tmpl"
+	}
+
+	return tmpl.ExecuteTemplate(w, name, v.templateData(r, data))
+}
+
+func (v *Render) executeJet(w io.Writer, r *http.Request, view string, variables, data interface{}) error {
+	// the variables are copied, so adding T doesn't change the caller's map
+	vars := make(jet.VarMap)
+	if variables != nil {
+		for name, value := range variables.(jet.VarMap) {
+			vars[name] = value
+		}
+	}
 
 	t, err := v.JetViews.GetTemplate(fmt.Sprintf("%s.jet", view))
 	if err != nil {
@@ -105,10 +246,100 @@ func (v *Render) JetPage(w http.ResponseWriter, r *http.Request, view string, va
 		return err
 	}
 
-	if err = t.Execute(w, vars, td); err != nil {
+	context := v.templateData(r, data)
+
+	// T("key") translates into the locale of the page
+	if td, ok := context.(*TemplateData); ok {
+		if _, exists := vars["T"]; !exists {
+			vars.Set("T", td.T)
+		}
+	}
+
+	if err = t.Execute(w, vars, context); err != nil {
 		log.Println(err)
 		return err
 	}
 
 	return nil
 }
+
+// AddGoFuncs registers functions for use in Go templates, and clears the template cache
+// so they are picked up
+func (v *Render) AddGoFuncs(funcs template.FuncMap) {
+	v.cacheMu.Lock()
+	defer v.cacheMu.Unlock()
+
+	if v.FuncMap == nil {
+		v.FuncMap = make(template.FuncMap)
+	}
+	for name, fn := range funcs {
+		v.FuncMap[name] = fn
+	}
+	v.templateCache = nil
+}
+
+// goTemplate returns the parsed template set for a view, from the cache when possible
+func (v *Render) goTemplate(view string) (*template.Template, error) {
+	if !v.Debug {
+		v.cacheMu.RLock()
+		tmpl, ok := v.templateCache[view]
+		v.cacheMu.RUnlock()
+		if ok {
+			return tmpl, nil
+		}
+	}
+
+	v.cacheMu.Lock()
+	defer v.cacheMu.Unlock()
+
+	tmpl, err := v.parseGoTemplate(view)
+	if err != nil {
+		return nil, err
+	}
+
+	if !v.Debug {
+		if v.templateCache == nil {
+			v.templateCache = make(map[string]*template.Template)
+		}
+		v.templateCache[view] = tmpl
+	}
+
+	return tmpl, nil
+}
+
+// parseGoTemplate parses a page along with all the layouts and partials in the views folder.
+// The page is parsed last, so the templates it defines win over the defaults of the blocks in
+// layouts. An empty view parses the layouts and partials only
+func (v *Render) parseGoTemplate(view string) (*template.Template, error) {
+	viewsPath := fmt.Sprintf("%s/views", v.RootPath)
+
+	name := "partials"
+	page := fmt.Sprintf("%s/%s.page.tmpl", viewsPath, view)
+	if view != "" {
+		name = filepath.Base(page)
+	}
+	tmpl := template.New(name).Funcs(v.FuncMap)
+
+	for _, pattern := range []string{"*.layout.tmpl", "*.partial.tmpl"} {
+		matches, err := filepath.Glob(filepath.Join(viewsPath, pattern))
+		if err != nil {
+			return nil, err
+		}
+		if len(matches) > 0 {
+			tmpl, err = tmpl.ParseFiles(matches...)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if view != "" {
+		var err error
+		tmpl, err = tmpl.ParseFiles(page)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return tmpl, nil
+}
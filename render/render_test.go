@@ -1,9 +1,16 @@
 package render
 
 import (
+	"encoding/gob"
+	"html/template"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/FernandoJVideira/velox/i18n"
+	"github.com/alexedwards/scs/v2"
 )
 
 var pageData = []struct {
@@ -71,3 +78,270 @@ func TestRender_JetPage(t *testing.T) {
 		t.Error("Error Rendering Page", err)
 	}
 }
+
+func TestRender_GoPageLayout(t *testing.T) {
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest("GET", "/some-url", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	testRenderer.Renderer = "go"
+	testRenderer.RootPath = "./testdata"
+	err = testRenderer.Page(w, r, "about", nil, nil)
+	if err != nil {
+		t.Fatal("Error Rendering Page", err)
+	}
+
+	body := w.Body.String()
+	if !strings.Contains(body, "<h1>About</h1>") {
+		t.Error("page content not rendered inside layout:", body)
+	}
+	if !strings.Contains(body, "<footer>VELOX</footer>") {
+		t.Error("partial not rendered with registered function:", body)
+	}
+	if strings.Contains(body, "No content") {
+		t.Error("the default of the layout's block replaced the page content:", body)
+	}
+
+	w = httptest.NewRecorder()
+	err = testRenderer.Page(w, r, "blank", nil, nil)
+	if err != nil {
+		t.Fatal("Error Rendering Page", err)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "<p>No content</p>") {
+		t.Error("the default of the layout's block was not rendered for a page without content:", body)
+	}
+}
+
+func TestRender_GoPageCache(t *testing.T) {
+	r, err := http.NewRequest("GET", "/some-url", nil)
+	if err != nil {
+		t.Error(err)
+	}
+
+	testRenderer.Renderer = "go"
+	testRenderer.RootPath = "./testdata"
+	testRenderer.templateCache = nil
+
+	testRenderer.Debug = true
+	err = testRenderer.Page(httptest.NewRecorder(), r, "about", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := testRenderer.templateCache["about"]; ok {
+		t.Error("template should not be cached in debug mode")
+	}
+
+	testRenderer.Debug = false
+	err = testRenderer.Page(httptest.NewRecorder(), r, "about", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := testRenderer.templateCache["about"]; !ok {
+		t.Error("template was not cached")
+	}
+
+	testRenderer.AddGoFuncs(template.FuncMap{"whisper": strings.ToLower})
+	if testRenderer.templateCache != nil {
+		t.Error("adding functions should clear the template cache")
+	}
+	if _, ok := testRenderer.FuncMap["whisper"]; !ok {
+		t.Error("function was not registered")
+	}
+}
+
+func TestRender_String(t *testing.T) {
+	testRenderer.RootPath = "./testdata"
+	data := struct{ Name string }{"velox"}
+
+	for _, renderer := range []string{"jet", "go"} {
+		testRenderer.Renderer = renderer
+
+		view := "item"
+		if renderer == "go" {
+			view = "home"
+		}
+		out, err := testRenderer.String(view, nil, data)
+		if err != nil {
+			t.Errorf("%s: %s", renderer, err)
+		}
+		if out == "" {
+			t.Errorf("%s: nothing rendered", renderer)
+		}
+	}
+
+	testRenderer.Renderer = "jet"
+	out, _ := testRenderer.String("item", nil, data)
+	if out != "<li>velox</li>" {
+		t.Error("wrong output rendering jet view to string:", out)
+	}
+
+	testRenderer.Renderer = "foo"
+	if _, err := testRenderer.String("home", nil, nil); err == nil {
+		t.Error("no error returned rendering with invalid renderer")
+	}
+}
+
+func TestRender_Bytes(t *testing.T) {
+	r, err := http.NewRequest("POST", "/some-url", strings.NewReader("email=me@here.com"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_ = r.ParseForm()
+
+	testRenderer.Renderer = "jet"
+	testRenderer.RootPath = "./testdata"
+	testRenderer.AddBuiltins()
+
+	out, err := testRenderer.Bytes(r, "funcs", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), "me@here.com") {
+		t.Error("default data from the request was not used:", string(out))
+	}
+}
+
+func TestRender_Partial(t *testing.T) {
+	testRenderer.RootPath = "./testdata"
+	data := struct{ Name string }{"velox"}
+
+	for _, renderer := range []string{"jet", "go"} {
+		testRenderer.Renderer = renderer
+		out, err := testRenderer.Partial(nil, "item", nil, data)
+		if err != nil {
+			t.Errorf("%s: %s", renderer, err)
+		}
+		if out != "<li>velox</li>" {
+			t.Errorf("%s: wrong partial output: %s", renderer, out)
+		}
+	}
+
+	testRenderer.Renderer = "go"
+	out, err := testRenderer.Partial(nil, "footer", nil, nil)
+	if err != nil {
+		t.Error(err)
+	}
+	if out != "<footer>VELOX</footer>" {
+		t.Error("partial was not found by its defined name:", out)
+	}
+
+	if _, err := testRenderer.Partial(nil, "no-partial", nil, nil); err == nil {
+		t.Error("no error returned rendering a partial that does not exist")
+	}
+}
+
+func TestRender_Translations(t *testing.T) {
+	translator := i18n.New("en")
+	translator.Add("en", map[string]interface{}{"greeting": "Hello, {name}"})
+	translator.Add("pt", map[string]interface{}{"greeting": "Olá, {name}"})
+
+	testRenderer.RootPath = "./testdata"
+	testRenderer.Translator = translator
+	defer func() {
+		testRenderer.Translator = nil
+	}()
+
+	tests := []struct {
+		renderer string
+		expected string
+	}{
+		{"jet", "Olá, Ana|Olá, Rui|pt"},
+		{"go", "Olá, Rui|pt"},
+	}
+
+	for _, e := range tests {
+		r, err := http.NewRequest("GET", "/some-url", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = r.WithContext(i18n.WithLocale(r.Context(), "pt"))
+
+		w := httptest.NewRecorder()
+		testRenderer.Renderer = e.renderer
+		err = testRenderer.Page(w, r, "translated", nil, nil)
+		if err != nil {
+			t.Fatalf("%s: %s", e.renderer, err)
+		}
+		if w.Body.String() != e.expected {
+			t.Errorf("%s: expected %q, got %q", e.renderer, e.expected, w.Body.String())
+		}
+	}
+
+	out, err := testRenderer.String("translated", nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(out, "Hello, Rui|en") {
+		t.Error("rendering without a request should use the default locale:", out)
+	}
+}
+
+func TestRender_JetVariablesUnchanged(t *testing.T) {
+	testRenderer.RootPath = "./testdata"
+	testRenderer.Renderer = "jet"
+
+	vars := make(jet.VarMap)
+	if _, err := testRenderer.String("translated", vars, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(vars) != 0 {
+		t.Errorf("expected the caller's variables to be left alone, got %v", vars)
+	}
+}
+
+func TestRender_StringLocale(t *testing.T) {
+	translator := i18n.New("en")
+	translator.Add("en", map[string]interface{}{"greeting": "Hello, {name}"})
+	translator.Add("pt", map[string]interface{}{"greeting": "Olá, {name}"})
+
+	testRenderer.RootPath = "./testdata"
+	testRenderer.Renderer = "jet"
+	testRenderer.Translator = translator
+	defer func() {
+		testRenderer.Translator = nil
+	}()
+
+	out, err := testRenderer.String("translated", nil, &TemplateData{Locale: "pt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != "Olá, Ana|Olá, Rui|pt" {
+		t.Errorf("expected the view in the locale of its data, got %q", out)
+	}
+}
+
+func TestRender_DefaultDataFlashedInput(t *testing.T) {
+	gob.Register(map[string]string{})
+	sess := scs.New()
+	renderer := Render{Session: sess}
+
+	var td *TemplateData
+	handler := sess.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/submit" {
+			sess.Put(r.Context(), "old", map[string]string{"email": "me@here.com"})
+			sess.Put(r.Context(), "errors", map[string]string{"name": "This field is required"})
+			return
+		}
+		td = renderer.DefaultData(&TemplateData{}, r)
+	}))
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("POST", "/submit", nil))
+	cookie := w.Result().Cookies()[0]
+
+	for i, expected := range []string{"me@here.com", ""} {
+		r := httptest.NewRequest("GET", "/form", nil)
+		r.AddCookie(cookie)
+		handler.ServeHTTP(httptest.NewRecorder(), r)
+
+		if got := td.Old["email"]; got != expected {
+			t.Errorf("request %d: expected old email %q, got %q", i, expected, got)
+		}
+		if expected != "" && td.Errors["name"] == "" {
+			t.Errorf("request %d: expected an error for name", i)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package render
 
 import (
+	"html/template"
 	"os"
+	"strings"
 	"testing"
 
 	"github.com/CloudyKit/jet/v6"
@@ -16,6 +18,9 @@ var testRenderer = Render{
 	Renderer: "",
 	RootPath: " ",
 	JetViews: views,
+	FuncMap: template.FuncMap{
+		"shout": strings.ToUpper,
+	},
 }
 
 func TestMain(m *testing.M) {
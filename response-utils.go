@@ -1,35 +1,146 @@
 package velox
 
 import (
+	"compress/gzip"
+	"encoding/gob"
 	"encoding/json"
 	"encoding/xml"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
+	"net/url"
 	"path"
 	"path/filepath"
+	"strconv"
+	"strings"
 
-	"errors"
+	"github.com/FernandoJVideira/velox/render"
+)
+
+// Errors returned by ReadJSON, wrapped in a *Problem. Use errors.Is to tell them apart
+var (
+	ErrJSONSyntax           = errors.New("body contains badly-formed JSON")
+	ErrJSONType             = errors.New("body contains an incorrect JSON type")
+	ErrJSONUnknownField     = errors.New("body contains an unknown field")
+	ErrJSONEmpty            = errors.New("body must not be empty")
+	ErrJSONMultipleValues   = errors.New("body must only contain a single JSON value")
+	ErrJSONTooLarge         = errors.New("body is too large")
+	ErrJSONInvalid          = errors.New("body failed validation")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
 )
 
-func (v *Velox) ReadJSON(w http.ResponseWriter, r *http.Request, data interface{}) error {
-	maxBytes := 1048576 // 1MB
-	r.Body = http.MaxBytesReader(w, r.Body, int64(maxBytes))
+// JSONOptions changes how ReadJSON reads a request body
+type JSONOptions struct {
+	// MaxBytes is the largest body accepted, after decompression; it defaults to 1MB
+	MaxBytes int64
+	// DisallowUnknownFields rejects bodies with fields the destination doesn't have
+	DisallowUnknownFields bool
+	// RequireContentType rejects requests whose Content-Type is not application/json
+	RequireContentType bool
+	// AllowGzip accepts bodies sent with Content-Encoding: gzip
+	AllowGzip bool
+	// Validate checks the decoded struct against its validate:"..." tags, see Validation.Struct
+	Validate bool
+}
+
+// ReadJSON decodes the JSON body of a request into data. The body must hold a single JSON
+// value of at most 1MB, unless options say otherwise. Errors are returned as a *Problem
+// describing what is wrong with the body, ready to be sent to the client with WriteProblem,
+// and wrap one of the ErrJSON errors
+func (v *Velox) ReadJSON(w http.ResponseWriter, r *http.Request, data interface{}, options ...JSONOptions) error {
+	var opts JSONOptions
+	if len(options) > 0 {
+		opts = options[0]
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = 1048576 // 1MB
+	}
+
+	if opts.RequireContentType {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		if mediaType != "application/json" {
+			return newProblem(http.StatusUnsupportedMediaType, ErrUnsupportedMediaType, "Content-Type must be application/json")
+		}
+	}
+
+	body := io.Reader(http.MaxBytesReader(w, r.Body, opts.MaxBytes))
+
+	switch encoding := strings.ToLower(r.Header.Get("Content-Encoding")); encoding {
+	case "", "identity":
+	case "gzip":
+		if !opts.AllowGzip {
+			return newProblem(http.StatusUnsupportedMediaType, ErrUnsupportedMediaType, "Content-Encoding gzip is not supported")
+		}
+		gz, err := gzip.NewReader(body)
+		if err != nil {
+			return jsonProblem(err)
+		}
+		defer gz.Close()
+		// the limit applies to the decompressed body too, so small bodies can't expand into huge ones
+		body = &limitedReader{r: gz, limit: opts.MaxBytes}
+	default:
+		return newProblem(http.StatusUnsupportedMediaType, ErrUnsupportedMediaType, fmt.Sprintf("Content-Encoding %s is not supported", encoding))
+	}
+
+	dec := json.NewDecoder(body)
+	if opts.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
 
-	dec := json.NewDecoder(r.Body)
 	err := dec.Decode(data)
 	if err != nil {
-		return err
+		return jsonProblem(err)
 	}
 
 	err = dec.Decode(&struct{}{})
+	var maxBytesError *http.MaxBytesError
+	if errors.As(err, &maxBytesError) {
+		return jsonProblem(err)
+	}
 	if err != io.EOF {
-		return errors.New("body must only have a single JSON value")
+		return newProblem(http.StatusBadRequest, ErrJSONMultipleValues, "Body must only contain a single JSON value")
+	}
+
+	if opts.Validate {
+		validation := v.ValidatorFor(r)
+		if err := validation.Struct(data); err != nil {
+			return newProblem(http.StatusInternalServerError, err, "")
+		}
+		if !validation.Valid() {
+			problem := validation.Problem()
+			problem.err = ErrJSONInvalid
+			return problem
+		}
 	}
 
 	return nil
 }
 
+// limitedReader fails with a MaxBytesError once more than limit bytes are read from r
+type limitedReader struct {
+	r     io.Reader
+	limit int64
+	read  int64
+}
+
+func (l *limitedReader) Read(p []byte) (int, error) {
+	if l.read > l.limit {
+		return 0, &http.MaxBytesError{Limit: l.limit}
+	}
+	if left := l.limit - l.read + 1; int64(len(p)) > left {
+		p = p[:left]
+	}
+
+	n, err := l.r.Read(p)
+	l.read += int64(n)
+	if l.read > l.limit {
+		return n - int(l.read-l.limit), &http.MaxBytesError{Limit: l.limit}
+	}
+	return n, err
+}
+
 // WriteJSON writes json from arbitrary data
 func (v *Velox) WriteJSON(w http.ResponseWriter, status int, data interface{}, headers ...http.Header) error {
 	out, err := json.Marshal(data)
@@ -83,28 +194,208 @@ func (v *Velox) DownloadFile(w http.ResponseWriter, r *http.Request, pathToFile,
 	return nil
 }
 
+func init() {
+	// old input and validation errors are kept in the session as maps
+	gob.Register(map[string]string{})
+}
+
+// RedirectBack redirects to the page the request came from, or to the home page when that page
+// is unknown or on another site
+func (v *Velox) RedirectBack(w http.ResponseWriter, r *http.Request) {
+	target := "/"
+	if referer, err := url.Parse(r.Referer()); err == nil && referer.Host == r.Host && referer.Path != "" {
+		target = referer.RequestURI()
+	}
+	http.Redirect(w, r, target, http.StatusSeeOther)
+}
+
+// RedirectBackWithErrors redirects to the page the request came from, flashing the errors of
+// validation and the submitted input (except passwords) through the session, so the form can be
+// shown again with its values and messages. The next page gets them in TemplateData.Old and
+// TemplateData.Errors
+func (v *Velox) RedirectBackWithErrors(w http.ResponseWriter, r *http.Request, validation *Validation) {
+	data := validation.Data
+	if data == nil {
+		data = r.Form
+	}
+
+	old := make(map[string]string)
+	for field, values := range data {
+		if len(values) == 0 || field == "csrf_token" || strings.Contains(strings.ToLower(field), "password") {
+			continue
+		}
+		old[field] = values[0]
+	}
+
+	v.Session.Put(r.Context(), "old", old)
+	v.Session.Put(r.Context(), "errors", validation.Errors)
+	v.RedirectBack(w, r)
+}
+
 // Error404 returns page not found response
 func (v *Velox) Error404(w http.ResponseWriter, r *http.Request) {
-	v.ErrorStatus(w, http.StatusNotFound)
+	v.HandleError(w, r, http.StatusNotFound, nil)
 }
 
 // Error500 returns internal server error response
 func (v *Velox) Error500(w http.ResponseWriter, r *http.Request) {
-	v.ErrorStatus(w, http.StatusInternalServerError)
+	v.HandleError(w, r, http.StatusInternalServerError, nil)
 }
 
 // ErrorUnauthorized sends an unauthorized status (client is not known)
 func (v *Velox) ErrorUnauthorized(w http.ResponseWriter, r *http.Request) {
-	v.ErrorStatus(w, http.StatusUnauthorized)
+	v.HandleError(w, r, http.StatusUnauthorized, nil)
 }
 
 // ErrorForbidden returns a forbidden status message (client is known)
 func (v *Velox) ErrorForbidden(w http.ResponseWriter, r *http.Request) {
-	v.ErrorStatus(w, http.StatusForbidden)
+	v.HandleError(w, r, http.StatusForbidden, nil)
 }
 
-// ErrorStatus returns a response with the supplied http status
+// ErrorStatus returns a response with the supplied http status. Without the request, the
+// error page is rendered without request data and API clients can't be told apart, so
+// prefer HandleError when the request is at hand
 func (v *Velox) ErrorStatus(w http.ResponseWriter, status int) {
-	http.Error(w, http.StatusText(status), status)
+	v.HandleError(w, nil, status, nil)
+}
+
+// Respond writes data in the format the client asks for in its Accept header: the rendered
+// view for browsers, or JSON or XML for API clients. Data may be a *render.TemplateData, whose
+// Data map is what API clients get, or any other value, which is passed to the view as
+// .Data (a map is used as is, anything else is available as .Data.data).
+// When the view is empty only JSON and XML are offered, and when no format is acceptable
+// a plain text 406 Not Acceptable status is sent
+func (v *Velox) Respond(w http.ResponseWriter, r *http.Request, status int, view string, data interface{}) error {
+	offers := []string{"text/html", "application/json", "application/xml"}
+	if view == "" {
+		offers = offers[1:]
+	}
+
+	var err error
+	switch negotiate(r.Header.Get("Accept"), offers) {
+	case "text/html":
+		err = v.respondHTML(w, r, status, view, data)
+	case "application/json":
+		err = v.WriteJSON(w, status, apiData(data))
+	case "application/xml":
+		err = v.WriteXML(w, status, apiData(data))
+	default:
+		// the client refused every format the error page could be sent in too
+		http.Error(w, http.StatusText(http.StatusNotAcceptable), http.StatusNotAcceptable)
+		return nil
+	}
+
+	if err != nil {
+		v.ErrorLog.Println(err)
+		v.Error500(w, r)
+	}
+	return err
+}
+
+// respondHTML renders a view with status. It renders into memory first, so that an error
+// can still be answered with a 500 status
+func (v *Velox) respondHTML(w http.ResponseWriter, r *http.Request, status int, view string, data interface{}) error {
+	var td *render.TemplateData
+	switch d := data.(type) {
+	case nil:
+	case *render.TemplateData:
+		td = d
+	case map[string]interface{}:
+		td = &render.TemplateData{Data: d}
+	default:
+		td = &render.TemplateData{Data: map[string]interface{}{"data": d}}
+	}
+
+	out, err := v.Render.Bytes(r, view, nil, td)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	_, err = w.Write(out)
+	return err
+}
+
+// apiData returns what API clients get for data; for template data, that is its Data map
+func apiData(data interface{}) interface{} {
+	if td, ok := data.(*render.TemplateData); ok {
+		return td.Data
+	}
+	return data
+}
+
+// negotiate returns the offer that best matches an Accept header, or an empty string when none
+// is acceptable. An offer's quality comes from the most specific range matching it, so ranges with
+// q=0 exclude offers a wildcard would accept. Ties go to the offer matched by the more specific
+// range, then to the earlier offer, since offers are listed in order of preference
+func negotiate(accept string, offers []string) string {
+	if strings.TrimSpace(accept) == "" {
+		return offers[0]
+	}
+
+	var ranges []mediaRange
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, quality := parseMediaRange(part)
+		ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+	}
+
+	best, bestQuality, bestSpecificity := "", 0.0, -1
+	for _, offer := range offers {
+		quality, specificity := 0.0, -1
+		for _, rng := range ranges {
+			s := rng.specificity(offer)
+			if s > specificity {
+				quality, specificity = rng.quality, s
+			}
+		}
+
+		if specificity < 0 || quality <= 0 {
+			continue
+		}
+		if quality > bestQuality || (quality == bestQuality && specificity > bestSpecificity) {
+			best, bestQuality, bestSpecificity = offer, quality, specificity
+		}
+	}
+
+	return best
+}
+
+// mediaRange is one of the ranges of an Accept header, such as text/* or application/json
+type mediaRange struct {
+	mediaType string
+	quality   float64
+}
+
+// specificity says how closely the range matches offer: 2 for the same type, 1 for a subtype
+// wildcard, 0 for */*, and -1 when it doesn't match
+func (m mediaRange) specificity(offer string) int {
+	switch {
+	case m.mediaType == offer:
+		return 2
+	case strings.HasSuffix(m.mediaType, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(m.mediaType, "*")):
+		return 1
+	case m.mediaType == "*/*":
+		return 0
+	}
+	return -1
+}
+
+// parseMediaRange splits a media range such as "application/json;q=0.8" into its type and quality
+func parseMediaRange(mediaRange string) (string, float64) {
+	params := strings.Split(mediaRange, ";")
+	mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+	quality := 1.0
+
+	for _, param := range params[1:] {
+		param = strings.TrimSpace(param)
+		if strings.HasPrefix(param, "q=") {
+			q, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+			if err == nil {
+				quality = q
+			}
+		}
+	}
 
+	return mediaType, quality
 }
@@ -0,0 +1,261 @@
+package velox
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+func TestNegotiate(t *testing.T) {
+	offers := []string{"text/html", "application/json", "application/xml"}
+
+	tests := []struct {
+		name     string
+		accept   string
+		expected string
+	}{
+		{"empty", "", "text/html"},
+		{"exact", "application/json", "application/json"},
+		{"browser", "text/html,application/xhtml+xml,application/xml;q=0.9,*/*;q=0.8", "text/html"},
+		{"quality", "text/html;q=0.5, application/xml", "application/xml"},
+		{"subtype wildcard", "application/*", "application/json"},
+		{"any", "*/*", "text/html"},
+		{"specific beats wildcard", "application/json, */*", "application/json"},
+		{"excluded by q=0", "text/html;q=0, */*", "application/json"},
+		{"wildcard excluded by q=0", "application/*;q=0, */*", "text/html"},
+		{"only excluded", "text/html;q=0", ""},
+		{"nothing acceptable", "image/png", ""},
+		{"case and spaces", " Application/JSON ; q=1", "application/json"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := negotiate(tt.accept, offers); got != tt.expected {
+				t.Errorf("expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestRespond(t *testing.T) {
+	v := newTestVelox()
+	data := map[string]interface{}{"greeting": "hello"}
+	type greeting struct {
+		Greeting string
+	}
+
+	tests := []struct {
+		name        string
+		accept      string
+		view        string
+		data        interface{}
+		status      int
+		contentType string
+		body        string
+	}{
+		{"html", "text/html", "respond", data, http.StatusCreated, "text/html", "<p>hello</p>"},
+		{"json", "application/json", "respond", data, http.StatusCreated, "application/json", `{"greeting":"hello"}`},
+		{"xml", "application/xml", "", greeting{"hello"}, http.StatusCreated, "application/xml", "<Greeting>hello</Greeting>"},
+		{"html refused", "text/html;q=0, */*", "respond", data, http.StatusCreated, "application/json", `{"greeting":"hello"}`},
+		{"no view", "text/html", "", data, http.StatusNotAcceptable, "text/plain", "Not Acceptable"},
+		{"not acceptable", "image/png", "respond", data, http.StatusNotAcceptable, "text/plain", "Not Acceptable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.Header.Set("Accept", tt.accept)
+			w := httptest.NewRecorder()
+
+			_ = v.Respond(w, r, http.StatusCreated, tt.view, tt.data)
+
+			if w.Code != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, w.Code)
+			}
+			if !strings.HasPrefix(w.Header().Get("Content-Type"), tt.contentType) {
+				t.Errorf("expected %s, got %s", tt.contentType, w.Header().Get("Content-Type"))
+			}
+			if !strings.Contains(w.Body.String(), tt.body) {
+				t.Errorf("expected %q in the body, got %q", tt.body, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestReadJSON_Validate(t *testing.T) {
+	type signup struct {
+		Name  string `json:"name" validate:"required"`
+		Email string `json:"email" validate:"required,email"`
+	}
+
+	tests := []struct {
+		name   string
+		body   string
+		data   interface{}
+		status int
+		err    error
+	}{
+		{"valid", `{"name":"ana","email":"ana@here.com"}`, &signup{}, 0, nil},
+		{"invalid", `{"name":"ana","email":"nope"}`, &signup{}, http.StatusUnprocessableEntity, ErrJSONInvalid},
+		{"not a struct", `{"name":"ana"}`, &map[string]string{}, http.StatusInternalServerError, nil},
+		{"bad tag", `{"name":"ana"}`, &struct {
+			Name string `json:"name" validate:"shiny"`
+		}{}, http.StatusInternalServerError, ErrValidationRule},
+	}
+
+	v := newTestVelox()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			err := v.ReadJSON(httptest.NewRecorder(), r, tt.data, JSONOptions{Validate: true})
+
+			if tt.status == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			var problem *Problem
+			if !errors.As(err, &problem) {
+				t.Fatalf("expected a problem, got %v", err)
+			}
+			if problem.Status != tt.status {
+				t.Errorf("expected status %d, got %d", tt.status, problem.Status)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+func TestReadJSON_Options(t *testing.T) {
+	gzipped := func(s string) string {
+		buf := new(bytes.Buffer)
+		gz := gzip.NewWriter(buf)
+		_, _ = gz.Write([]byte(s))
+		_ = gz.Close()
+		return buf.String()
+	}
+	large := `{"name": "` + strings.Repeat("a", 1<<20) + `"}`
+
+	tests := []struct {
+		name        string
+		opts        JSONOptions
+		body        string
+		contentType string
+		encoding    string
+		status      int
+		err         error
+	}{
+		{"defaults", JSONOptions{}, `{"name": "ana"}`, "", "", 0, nil},
+		{"default max bytes", JSONOptions{}, large, "", "", http.StatusRequestEntityTooLarge, ErrJSONTooLarge},
+		{"max bytes", JSONOptions{MaxBytes: 16}, `{"name": "ana"}`, "", "", 0, nil},
+		{"over max bytes", JSONOptions{MaxBytes: 8}, `{"name": "ana"}`, "", "", http.StatusRequestEntityTooLarge, ErrJSONTooLarge},
+		{"larger max bytes", JSONOptions{MaxBytes: 2 << 20}, large, "", "", 0, nil},
+		{"unknown fields allowed", JSONOptions{}, `{"name": "ana", "nick": "an"}`, "", "", 0, nil},
+		{"unknown fields disallowed", JSONOptions{DisallowUnknownFields: true}, `{"name": "ana", "nick": "an"}`, "", "", http.StatusBadRequest, ErrJSONUnknownField},
+		{"content type not required", JSONOptions{}, `{"name": "ana"}`, "text/plain", "", 0, nil},
+		{"content type", JSONOptions{RequireContentType: true}, `{"name": "ana"}`, "application/json; charset=utf-8", "", 0, nil},
+		{"missing content type", JSONOptions{RequireContentType: true}, `{"name": "ana"}`, "", "", http.StatusUnsupportedMediaType, ErrUnsupportedMediaType},
+		{"wrong content type", JSONOptions{RequireContentType: true}, `{"name": "ana"}`, "text/plain", "", http.StatusUnsupportedMediaType, ErrUnsupportedMediaType},
+		{"gzip", JSONOptions{AllowGzip: true}, gzipped(`{"name": "ana"}`), "", "gzip", 0, nil},
+		{"gzip not allowed", JSONOptions{}, gzipped(`{"name": "ana"}`), "", "gzip", http.StatusUnsupportedMediaType, ErrUnsupportedMediaType},
+		{"bad gzip", JSONOptions{AllowGzip: true}, `{"name": "ana"}`, "", "gzip", http.StatusBadRequest, ErrJSONSyntax},
+		{"gzip over max bytes", JSONOptions{AllowGzip: true, MaxBytes: 1 << 10}, gzipped(large), "", "gzip", http.StatusRequestEntityTooLarge, ErrJSONTooLarge},
+		{"other encoding", JSONOptions{AllowGzip: true}, `{"name": "ana"}`, "", "br", http.StatusUnsupportedMediaType, ErrUnsupportedMediaType},
+	}
+
+	v := newTestVelox()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				r.Header.Set("Content-Type", tt.contentType)
+			}
+			if tt.encoding != "" {
+				r.Header.Set("Content-Encoding", tt.encoding)
+			}
+
+			var data struct {
+				Name string `json:"name"`
+			}
+			err := v.ReadJSON(httptest.NewRecorder(), r, &data, tt.opts)
+
+			if tt.status == 0 {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if !strings.HasPrefix(data.Name, "a") {
+					t.Errorf("expected the body to be decoded, got %+v", data)
+				}
+				return
+			}
+			var problem *Problem
+			if !errors.As(err, &problem) {
+				t.Fatalf("expected a problem, got %v", err)
+			}
+			if problem.Status != tt.status || !errors.Is(err, tt.err) {
+				t.Errorf("expected %d %v, got %d %v", tt.status, tt.err, problem.Status, err)
+			}
+		})
+	}
+}
+
+func TestRedirectBackWithErrors(t *testing.T) {
+	v := newTestVelox()
+	v.Session = scs.New()
+	v.CreateRenderer()
+
+	handler := v.Session.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost {
+			_ = r.ParseForm()
+			validation := v.ValidatorFor(r)
+			validation.Required(r, "name", "email")
+			validation.IsEmail("email", r.Form.Get("email"))
+			if !validation.Valid() {
+				v.RedirectBackWithErrors(w, r, validation)
+			}
+			return
+		}
+		if err := v.Render.Page(w, r, "form", nil, nil); err != nil {
+			t.Error(err)
+		}
+	}))
+
+	form := url.Values{"name": {"ana"}, "email": {"nope"}, "password": {"secret"}, "csrf_token": {"token"}}
+	r := httptest.NewRequest("POST", "http://example.com/users", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.Header.Set("Referer", "http://example.com/users/new?step=2")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if w.Code != http.StatusSeeOther || w.Header().Get("Location") != "/users/new?step=2" {
+		t.Fatalf("expected a redirect back to the form, got %d %q", w.Code, w.Header().Get("Location"))
+	}
+	cookies := w.Result().Cookies()
+	if len(cookies) == 0 {
+		t.Fatal("expected a session cookie")
+	}
+
+	for i, expected := range []string{
+		"nope|none|true|false|Invalid email address",
+		"|none|false|false|",
+	} {
+		r := httptest.NewRequest("GET", "http://example.com/users/new?step=2", nil)
+		r.AddCookie(cookies[0])
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+
+		if got := strings.TrimSpace(w.Body.String()); got != expected {
+			t.Errorf("request %d: expected %q, got %q", i, expected, got)
+		}
+	}
+}
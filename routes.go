@@ -14,11 +14,18 @@ func (v *Velox) routes() http.Handler {
 	if v.Debug {
 		mux.Use(middleware.Logger)
 	}
-	mux.Use(middleware.Recoverer)
+	mux.Use(v.Recoverer)
 	mux.Use(v.SessionLoad)
+	mux.Use(v.TrackSession)
+	mux.Use(v.DetectLocale)
 	mux.Use(v.NoSurf)
 	mux.Use(v.CheckForMaintenanceMode)
 
+	mux.NotFound(v.Error404)
+	mux.MethodNotAllowed(func(w http.ResponseWriter, r *http.Request) {
+		v.HandleError(w, r, http.StatusMethodNotAllowed, nil)
+	})
+
 	return mux
 }
 
@@ -0,0 +1,262 @@
+package session
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// Keys used to store session activity alongside the session data
+const (
+	UserIDKey    = "userID"
+	ipKey        = "_velox_ip"
+	userAgentKey = "_velox_user_agent"
+	createdKey   = "_velox_created"
+	lastSeenKey  = "_velox_last_seen"
+)
+
+// lastSeenInterval is how often the last seen time is refreshed, so that
+// sessions are not written back to the store on every single request
+const lastSeenInterval = time.Minute
+
+var (
+	ErrNotIterable     = errors.New("session store does not support iteration")
+	ErrSessionNotFound = errors.New("session not found")
+)
+
+func init() {
+	// activity times are stored as interface values, which gob must know about
+	gob.Register(time.Time{})
+}
+
+// ActiveSession describes one of a user's active sessions
+type ActiveSession struct {
+	ID        string
+	UserID    int
+	IPAddress string
+	UserAgent string
+	Device    string
+	CreatedAt time.Time
+	LastSeen  time.Time
+	Expires   time.Time
+	Current   bool
+}
+
+// Track is a middleware that records the ip address, user agent and last seen time
+// of authenticated sessions. It must run after the session has been loaded
+func Track(sm *scs.SessionManager) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := r.Context()
+			if sm.Exists(ctx, UserIDKey) {
+				now := time.Now()
+				ip := clientIP(r)
+
+				if !sm.Exists(ctx, createdKey) {
+					sm.Put(ctx, createdKey, now)
+				}
+				if sm.GetString(ctx, ipKey) != ip {
+					sm.Put(ctx, ipKey, ip)
+				}
+				if sm.GetString(ctx, userAgentKey) != r.UserAgent() {
+					sm.Put(ctx, userAgentKey, r.UserAgent())
+				}
+				if now.Sub(sm.GetTime(ctx, lastSeenKey)) > lastSeenInterval {
+					sm.Put(ctx, lastSeenKey, now)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UserSessions returns all active sessions belonging to userID, most recently seen first.
+// The session attached to ctx, if any, is flagged as Current
+func UserSessions(ctx context.Context, sm *scs.SessionManager, userID int) ([]ActiveSession, error) {
+	var sessions []ActiveSession
+	current := currentToken(ctx, sm)
+
+	err := iterateUser(ctx, sm, userID, func(c context.Context, token string) error {
+		sessions = append(sessions, ActiveSession{
+			ID:        sessionID(token),
+			UserID:    userID,
+			IPAddress: sm.GetString(c, ipKey),
+			UserAgent: sm.GetString(c, userAgentKey),
+			Device:    Device(sm.GetString(c, userAgentKey)),
+			CreatedAt: sm.GetTime(c, createdKey),
+			LastSeen:  sm.GetTime(c, lastSeenKey),
+			Expires:   sm.Deadline(c),
+			Current:   token != "" && token == current,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastSeen.After(sessions[j].LastSeen)
+	})
+
+	return sessions, nil
+}
+
+// RevokeSession destroys the session of userID identified by id, as returned in ActiveSession.ID
+func RevokeSession(ctx context.Context, sm *scs.SessionManager, userID int, id string) error {
+	current := currentToken(ctx, sm)
+	revokedCurrent := false
+	found := false
+	err := iterateUser(ctx, sm, userID, func(c context.Context, token string) error {
+		if sessionID(token) != id {
+			return nil
+		}
+		found = true
+		revokedCurrent = token != "" && token == current
+		return sm.Destroy(c)
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return ErrSessionNotFound
+	}
+
+	// as in revoke, the session of the request has to be destroyed too, or LoadAndSave writes it back
+	if revokedCurrent {
+		return sm.Destroy(ctx)
+	}
+	return nil
+}
+
+// RevokeOtherSessions destroys every session of userID except the one attached to ctx,
+// and returns the number of sessions destroyed
+func RevokeOtherSessions(ctx context.Context, sm *scs.SessionManager, userID int) (int, error) {
+	return revoke(ctx, sm, userID, currentToken(ctx, sm))
+}
+
+// RevokeAllSessions destroys every session of userID, including the one attached to ctx,
+// and returns the number of sessions destroyed
+func RevokeAllSessions(ctx context.Context, sm *scs.SessionManager, userID int) (int, error) {
+	return revoke(ctx, sm, userID, "")
+}
+
+// Device returns a short, human readable description of the browser and
+// operating system found in a user agent string
+func Device(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+	ua := strings.ToLower(userAgent)
+
+	browser := "Unknown browser"
+	switch {
+	case strings.Contains(ua, "edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "opr/"), strings.Contains(ua, "opera"):
+		browser = "Opera"
+	case strings.Contains(ua, "firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "chrome/"), strings.Contains(ua, "crios/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "safari/"):
+		browser = "Safari"
+	case strings.Contains(ua, "curl/"):
+		browser = "curl"
+	}
+
+	os := "Unknown OS"
+	switch {
+	case strings.Contains(ua, "iphone"), strings.Contains(ua, "ipad"):
+		os = "iOS"
+	case strings.Contains(ua, "android"):
+		os = "Android"
+	case strings.Contains(ua, "windows"):
+		os = "Windows"
+	case strings.Contains(ua, "mac os"), strings.Contains(ua, "macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "linux"):
+		os = "Linux"
+	}
+
+	return browser + " on " + os
+}
+
+// revoke destroys every session of userID except the one with the token keep
+func revoke(ctx context.Context, sm *scs.SessionManager, userID int, keep string) (int, error) {
+	count := 0
+	err := iterateUser(ctx, sm, userID, func(c context.Context, token string) error {
+		if keep != "" && token == keep {
+			return nil
+		}
+		count++
+		return sm.Destroy(c)
+	})
+
+	// destroying a session does not remove it from the current request, so do it here
+	// to stop LoadAndSave from writing it back to the store
+	if err == nil && keep == "" && hasSession(ctx, sm) && sm.GetInt(ctx, UserIDKey) == userID {
+		err = sm.Destroy(ctx)
+	}
+
+	return count, err
+}
+
+// iterateUser calls fn for every active session in the store that belongs to userID. It reads
+// every session in the store rather than keeping an index of each user's sessions: scs stores only
+// look sessions up by token, and have no way to update an index beside them atomically, so an
+// index could miss a session created during a concurrent login, and a forced logout after a
+// password reset must never miss one. Listing and revoking sessions is rare enough, from account
+// pages and password resets, for the cost of reading them all to be acceptable
+func iterateUser(ctx context.Context, sm *scs.SessionManager, userID int, fn func(context.Context, string) error) error {
+	switch sm.Store.(type) {
+	case scs.IterableStore, scs.IterableCtxStore:
+	default:
+		return ErrNotIterable
+	}
+
+	return sm.Iterate(ctx, func(c context.Context) error {
+		if sm.GetInt(c, UserIDKey) != userID {
+			return nil
+		}
+		return fn(c, sm.Token(c))
+	})
+}
+
+// currentToken returns the token of the session attached to ctx, or an empty string
+func currentToken(ctx context.Context, sm *scs.SessionManager) string {
+	if !hasSession(ctx, sm) {
+		return ""
+	}
+	return sm.Token(ctx)
+}
+
+// hasSession reports whether ctx carries session data. Load returns ctx itself when it does, and
+// otherwise only adds a new session to a copy of it, without reading the store
+func hasSession(ctx context.Context, sm *scs.SessionManager) bool {
+	loaded, err := sm.Load(ctx, "")
+	return err == nil && loaded == ctx
+}
+
+// sessionID derives a public identifier from a session token, so the token itself is never exposed
+func sessionID(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:12])
+}
+
+// clientIP returns the ip address of the request, without the port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,192 @@
+package session
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/alexedwards/scs/v2"
+)
+
+// login creates a session for userID through the Track middleware, as a browser with userAgent would
+func login(t *testing.T, sm *scs.SessionManager, userID int, userAgent string) string {
+	handler := sm.LoadAndSave(Track(sm)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sm.Put(r.Context(), UserIDKey, userID)
+	})))
+
+	// first request logs the user in, second one is seen by Track
+	var token string
+	for i := 0; i < 2; i++ {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("User-Agent", userAgent)
+		if token != "" {
+			r.AddCookie(&http.Cookie{Name: sm.Cookie.Name, Value: token})
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		for _, c := range w.Result().Cookies() {
+			if c.Name == sm.Cookie.Name {
+				token = c.Value
+			}
+		}
+	}
+
+	if token == "" {
+		t.Fatal("no session cookie returned")
+	}
+	return token
+}
+
+func TestSession_UserSessions(t *testing.T) {
+	sm := scs.New()
+	login(t, sm, 1, "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0) AppleWebKit/605.1.15 Version/17.0 Safari/605.1.15")
+	login(t, sm, 1, "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Chrome/120.0 Safari/537.36")
+	login(t, sm, 2, "curl/8.0")
+
+	sessions, err := UserSessions(context.Background(), sm, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+
+	for _, s := range sessions {
+		if s.IPAddress != "192.0.2.1" {
+			t.Error("wrong ip address:", s.IPAddress)
+		}
+		if s.LastSeen.IsZero() || s.CreatedAt.IsZero() {
+			t.Error("activity times were not recorded")
+		}
+		if s.Device != "Safari on macOS" && s.Device != "Chrome on Windows" {
+			t.Error("unexpected device:", s.Device)
+		}
+	}
+}
+
+func TestSession_RevokeSession(t *testing.T) {
+	sm := scs.New()
+	login(t, sm, 1, "curl/8.0")
+	login(t, sm, 1, "curl/8.0")
+
+	sessions, _ := UserSessions(context.Background(), sm, 1)
+
+	err := RevokeSession(context.Background(), sm, 1, sessions[0].ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remaining, _ := UserSessions(context.Background(), sm, 1)
+	if len(remaining) != 1 || remaining[0].ID == sessions[0].ID {
+		t.Error("session was not revoked")
+	}
+
+	err = RevokeSession(context.Background(), sm, 2, remaining[0].ID)
+	if err != ErrSessionNotFound {
+		t.Error("expected ErrSessionNotFound when revoking another user's session, got", err)
+	}
+}
+
+func TestSession_RevokeOtherSessions(t *testing.T) {
+	sm := scs.New()
+	current := login(t, sm, 1, "curl/8.0")
+	login(t, sm, 1, "curl/8.0")
+	login(t, sm, 1, "curl/8.0")
+	login(t, sm, 2, "curl/8.0")
+
+	ctx, err := sm.Load(context.Background(), current)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := RevokeOtherSessions(ctx, sm, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("expected 2 sessions revoked, got %d", n)
+	}
+
+	sessions, _ := UserSessions(ctx, sm, 1)
+	if len(sessions) != 1 || !sessions[0].Current {
+		t.Error("current session should be the only one left")
+	}
+
+	n, err = RevokeAllSessions(context.Background(), sm, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 session revoked, got %d", n)
+	}
+}
+
+func TestSession_Device(t *testing.T) {
+	tests := map[string]string{
+		"":         "Unknown device",
+		"curl/8.0": "curl on Unknown OS",
+		"Mozilla/5.0 (Linux; Android 14) AppleWebKit/537.36 Chrome/120.0 Mobile Safari/537.36":    "Chrome on Android",
+		"Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) Version/17.0 Mobile Safari/604.1": "Safari on iOS",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:121.0) Gecko/20100101 Firefox/121.0":                  "Firefox on Linux",
+	}
+
+	for ua, expected := range tests {
+		if got := Device(ua); got != expected {
+			t.Errorf("%q: expected %s, got %s", ua, expected, got)
+		}
+	}
+}
+
+func TestSession_RevokeCurrentSession(t *testing.T) {
+	sm := scs.New()
+	current := login(t, sm, 1, "curl/8.0")
+	login(t, sm, 1, "curl/8.0")
+
+	handler := sm.LoadAndSave(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sessions, err := UserSessions(r.Context(), sm, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, s := range sessions {
+			if s.Current {
+				if err := RevokeSession(r.Context(), sm, 1, s.ID); err != nil {
+					t.Fatal(err)
+				}
+				// a change to the session after it is revoked would have it written back
+				sm.Put(r.Context(), "flash", "Signed out")
+			}
+		}
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: sm.Cookie.Name, Value: current})
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	sessions, _ := UserSessions(context.Background(), sm, 1)
+	if len(sessions) != 1 {
+		t.Fatalf("expected the current session to stay revoked after the request, got %d sessions", len(sessions))
+	}
+	if _, found, _ := sm.Store.Find(current); found {
+		t.Error("the revoked session was written back to the store")
+	}
+}
+
+func TestSession_HasSession(t *testing.T) {
+	sm := scs.New()
+	if hasSession(context.Background(), sm) {
+		t.Error("expected no session in an empty context")
+	}
+
+	ctx, err := sm.Load(context.Background(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasSession(ctx, sm) {
+		t.Error("expected the loaded session to be found")
+	}
+	if hasSession(ctx, scs.New()) {
+		t.Error("expected no session of another manager")
+	}
+}
@@ -16,13 +16,19 @@ import (
 
 type Session struct {
 	CookieLifetime string
+	IdleTimeout    string
 	CookiePersist  string
 	CookieName     string
 	CookieDomain   string
 	CookieSecure   string
-	SessionType    string
-	DBPool         *sql.DB
-	RedisPool      *redis.Pool
+	CookiePath     string
+	CookieSameSite string
+	CookieHTTPOnly string
+	// CookiePartitioned forces secure cookies, since browsers reject partitioned ones that are not
+	CookiePartitioned string
+	SessionType       string
+	DBPool            *sql.DB
+	RedisPool         *redis.Pool
 }
 
 func (v *Session) InitSession() *scs.SessionManager {
@@ -34,6 +40,12 @@ func (v *Session) InitSession() *scs.SessionManager {
 		minutes = 60
 	}
 
+	//How long the session may be inactive before it expires (0 means no idle timeout)
+	idleMinutes, err := strconv.Atoi(v.IdleTimeout)
+	if err != nil {
+		idleMinutes = 0
+	}
+
 	//Should the session persist after the browser is closed
 	if strings.ToLower(v.CookiePersist) == "true" {
 		persist = true
@@ -44,14 +56,25 @@ func (v *Session) InitSession() *scs.SessionManager {
 		secure = true
 	}
 
+	sameSite := ParseSameSite(v.CookieSameSite)
+	if sameSite == http.SameSiteNoneMode || strings.ToLower(v.CookiePartitioned) == "true" {
+		//Browsers reject SameSite=None and partitioned cookies that are not secure
+		secure = true
+	}
+
 	//Create the session
 	session := scs.New()
 	session.Lifetime = time.Duration(minutes) * time.Minute
+	session.IdleTimeout = time.Duration(idleMinutes) * time.Minute
 	session.Cookie.Persist = persist
 	session.Cookie.Name = v.CookieName
 	session.Cookie.Secure = secure
 	session.Cookie.Domain = v.CookieDomain
-	session.Cookie.SameSite = http.SameSiteLaxMode
+	session.Cookie.SameSite = sameSite
+	session.Cookie.HttpOnly = strings.ToLower(v.CookieHTTPOnly) != "false"
+	if v.CookiePath != "" {
+		session.Cookie.Path = v.CookiePath
+	}
 
 	//Which session type to use
 	switch strings.ToLower(v.SessionType) {
@@ -67,3 +90,16 @@ func (v *Session) InitSession() *scs.SessionManager {
 
 	return session
 }
+
+// ParseSameSite converts a SameSite setting (lax, strict or none) into its http.SameSite value.
+// Anything else defaults to lax
+func ParseSameSite(sameSite string) http.SameSite {
+	switch strings.ToLower(strings.TrimSpace(sameSite)) {
+	case "strict":
+		return http.SameSiteStrictMode
+	case "none":
+		return http.SameSiteNoneMode
+	default:
+		return http.SameSiteLaxMode
+	}
+}
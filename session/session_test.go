@@ -2,8 +2,10 @@ package session
 
 import (
 	"fmt"
+	"net/http"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/alexedwards/scs/v2"
 )
@@ -43,3 +45,63 @@ func TestSession_InitSession(t *testing.T) {
 	}
 
 }
+
+func TestSession_CookieSettings(t *testing.T) {
+	v := &Session{
+		CookieLifetime: "60",
+		IdleTimeout:    "20",
+		CookieName:     "velox",
+		CookieSecure:   "false",
+		CookiePath:     "/app",
+		CookieSameSite: "none",
+		CookieHTTPOnly: "false",
+		SessionType:    "cookie",
+	}
+	sm := v.InitSession()
+
+	if sm.IdleTimeout != 20*time.Minute {
+		t.Error("wrong idle timeout:", sm.IdleTimeout)
+	}
+	if sm.Cookie.Path != "/app" {
+		t.Error("wrong cookie path:", sm.Cookie.Path)
+	}
+	if sm.Cookie.SameSite != http.SameSiteNoneMode {
+		t.Error("wrong samesite mode:", sm.Cookie.SameSite)
+	}
+	if !sm.Cookie.Secure {
+		t.Error("samesite none cookies must be secure")
+	}
+	if sm.Cookie.HttpOnly {
+		t.Error("cookie should not be http only")
+	}
+}
+
+func TestSession_PartitionedCookie(t *testing.T) {
+	v := &Session{
+		CookieName:        "velox",
+		CookieSecure:      "false",
+		CookiePartitioned: "true",
+		SessionType:       "cookie",
+	}
+	sm := v.InitSession()
+
+	if !sm.Cookie.Secure {
+		t.Error("partitioned cookies must be secure")
+	}
+}
+
+func TestSession_ParseSameSite(t *testing.T) {
+	tests := map[string]http.SameSite{
+		"":        http.SameSiteLaxMode,
+		"Lax":     http.SameSiteLaxMode,
+		"strict":  http.SameSiteStrictMode,
+		"NONE":    http.SameSiteNoneMode,
+		"unknown": http.SameSiteLaxMode,
+	}
+
+	for in, expected := range tests {
+		if got := ParseSameSite(in); got != expected {
+			t.Errorf("%q: expected %v, got %v", in, expected, got)
+		}
+	}
+}
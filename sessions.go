@@ -0,0 +1,34 @@
+package velox
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/FernandoJVideira/velox/session"
+)
+
+// TrackSession records the ip address, user agent and last seen time of logged in sessions
+func (v *Velox) TrackSession(next http.Handler) http.Handler {
+	return session.Track(v.Session)(next)
+}
+
+// UserSessions lists the active sessions of a user, flagging the one making the request as current
+func (v *Velox) UserSessions(r *http.Request, userID int) ([]session.ActiveSession, error) {
+	return session.UserSessions(r.Context(), v.Session, userID)
+}
+
+// RevokeSession logs a user out of a single session, identified by its ActiveSession.ID
+func (v *Velox) RevokeSession(r *http.Request, userID int, id string) error {
+	return session.RevokeSession(r.Context(), v.Session, userID, id)
+}
+
+// RevokeOtherSessions logs a user out everywhere except the session making the request
+func (v *Velox) RevokeOtherSessions(r *http.Request, userID int) (int, error) {
+	return session.RevokeOtherSessions(r.Context(), v.Session, userID)
+}
+
+// RevokeUserSessions logs a user out of every session, e.g. after a password reset.
+// The context may come from a request or, for background jobs, context.Background()
+func (v *Velox) RevokeUserSessions(ctx context.Context, userID int) (int, error) {
+	return session.RevokeAllSessions(ctx, v.Session, userID)
+}
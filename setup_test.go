@@ -0,0 +1,37 @@
+package velox
+
+import (
+	"io"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/CloudyKit/jet/v6"
+	"github.com/FernandoJVideira/velox/render"
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(m.Run())
+}
+
+// newTestVelox returns a Velox rendering the jet views in testdata/views, which logs nothing
+func newTestVelox() *Velox {
+	views := jet.NewSet(
+		jet.NewOSFileSystemLoader("./testdata/views"),
+		jet.InDevelopmentMode(),
+	)
+
+	v := &Velox{
+		RootPath: "./testdata",
+		ErrorLog: log.New(io.Discard, "", 0),
+		InfoLog:  log.New(io.Discard, "", 0),
+		JetViews: views,
+	}
+	v.config.renderer = "jet"
+	v.Render = &render.Render{
+		Renderer: "jet",
+		RootPath: v.RootPath,
+		JetViews: views,
+	}
+	return v
+}
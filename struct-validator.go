@@ -0,0 +1,532 @@
+package velox
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/asaskevich/govalidator"
+)
+
+// ErrValidationRule is returned for validate tags that can't be checked, such as unknown rules
+// or a min rule without a number
+var ErrValidationRule = errors.New("velox: invalid validation rule")
+
+// structRule checks a field value against a validate tag rule, returning the error message when
+// the value fails, or an empty string when it passes. Param is what follows the = in the tag,
+// and parent is the struct holding the field. The error is only set when the rule can't be
+// checked, because of its parameter or the database
+type structRule func(v *Validation, value reflect.Value, param string, parent reflect.Value) (string, error)
+
+// structRules holds the rules that can be used in validate tags
+var structRules = map[string]structRule{
+	"required":  ruleRequired,
+	"email":     ruleEmail,
+	"min":       ruleMin,
+	"max":       ruleMax,
+	"oneof":     ruleOneOf,
+	"in":        ruleOneOf,
+	"notin":     ruleNotIn,
+	"regex":     ruleRegex,
+	"url":       ruleURL,
+	"uuid":      ruleUUID,
+	"ip":        ruleIP,
+	"alphanum":  ruleAlphaNumeric,
+	"same":      ruleSame,
+	"confirmed": ruleConfirmed,
+	"password":  rulePassword,
+	"date":      ruleDate,
+	"before":    ruleBefore,
+	"after":     ruleAfter,
+	"unique":    ruleUnique,
+	"exists":    ruleExists,
+}
+
+// ValidateStruct checks dst, a struct or a pointer to one, against the validate tags of its
+// fields and returns the result. See Validation.Struct for the tag format and the errors
+func (v *Velox) ValidateStruct(dst interface{}) (*Validation, error) {
+	validation := v.Validator(nil)
+	err := validation.Struct(dst)
+	return validation, err
+}
+
+// Struct checks dst, a struct or a pointer to one, against the validate tags of its fields,
+// adding an error for each field that fails. Rules are separated by commas, and take their
+// parameter after an equals sign:
+//
+//	Name  string `json:"name" validate:"required,min=3,max=255"`
+//	Email string `json:"email" validate:"required,email"`
+//	Role  string `json:"role" validate:"oneof=admin editor viewer"`
+//
+// The rules are required, email, min and max (length of strings and slices, value of numbers),
+// oneof or in, notin, regex (which takes the rest of the tag, commas included, so it comes last),
+// url, uuid, ip, alphanum, same=OtherField (an exported field), confirmed (the field must match <Field>Confirmation
+// or <name>_confirmation), password=<min length>, date=<layout>, before and after (a 2006-01-02
+// date or now), and unique and exists, which take a table.column and query the database.
+//
+// Required fails for zero values, so a 0 or false int or bool field fails it; use a pointer, such
+// as *int or *bool, for fields where those are valid answers.
+//
+// Errors are keyed by the form or json name of the field. Nested structs and slices of structs
+// are validated too, with keys such as address.city or items.0.name. Rules other than required
+// are skipped for empty strings, slices and maps and nil pointers, so optional fields can still
+// have a format.
+//
+// The error is set when dst is not a struct, when a tag can't be checked (wrapping
+// ErrValidationRule), or when the database can't be queried by unique or exists; the other fields
+// are still checked
+func (v *Validation) Struct(dst interface{}) error {
+	value := reflect.Indirect(reflect.ValueOf(dst))
+	if value.Kind() != reflect.Struct {
+		return fmt.Errorf("velox: cannot validate %T, it is not a struct", dst)
+	}
+	return v.validateStruct(value, "")
+}
+
+func (v *Validation) validateStruct(value reflect.Value, prefix string) error {
+	var errs []error
+	t := value.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		name := fieldName(field)
+		if name == "-" {
+			continue
+		}
+		key := prefix + name
+
+		fieldValue := value.Field(i)
+		if tag := field.Tag.Get("validate"); tag != "" && tag != "-" {
+			errs = append(errs, v.validateField(key, field, fieldValue, tag, value))
+		}
+		errs = append(errs, v.validateNested(key, fieldValue))
+	}
+	return errors.Join(errs...)
+}
+
+// validateNested validates the fields of a struct, or of the structs in a slice
+func (v *Validation) validateNested(key string, value reflect.Value) error {
+	value = reflect.Indirect(value)
+	if !value.IsValid() {
+		return nil
+	}
+
+	switch value.Kind() {
+	case reflect.Struct:
+		if value.Type() != timeType {
+			return v.validateStruct(value, key+".")
+		}
+	case reflect.Slice, reflect.Array:
+		var errs []error
+		for i := 0; i < value.Len(); i++ {
+			errs = append(errs, v.validateNested(key+"."+strconv.Itoa(i), value.Index(i)))
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
+
+func (v *Validation) validateField(key string, field reflect.StructField, value reflect.Value, tag string, parent reflect.Value) error {
+	for _, rule := range parseTag(tag) {
+		check, ok := structRules[rule.name]
+		if !ok {
+			return fmt.Errorf("%w: unknown rule %q on field %s", ErrValidationRule, rule.name, key)
+		}
+
+		if rule.name != "required" && absent(value) {
+			continue
+		}
+
+		param := rule.param
+		if rule.name == "confirmed" && param == "" {
+			param = field.Name + "Confirmation"
+			if _, _, ok := siblingField(parent, param); !ok {
+				param = fieldName(field) + "_confirmation"
+			}
+		}
+
+		message, err := check(v, value, param, parent)
+		if err != nil {
+			return fmt.Errorf("field %s: %w", key, err)
+		}
+		if message != "" {
+			v.AddError(key, message)
+			return nil
+		}
+	}
+	return nil
+}
+
+// tagRule is one of the rules of a validate tag, such as min=3
+type tagRule struct {
+	name  string
+	param string
+}
+
+// parseTag splits a validate tag into its rules. A regex rule takes the rest of the tag as its
+// pattern, commas included
+func parseTag(tag string) []tagRule {
+	var rules []tagRule
+	for tag != "" {
+		var rule string
+		rule, tag, _ = strings.Cut(tag, ",")
+		rule = strings.TrimSpace(rule)
+		if strings.HasPrefix(rule, "regex=") && tag != "" {
+			rule += "," + tag
+			tag = ""
+		}
+
+		name, param, _ := strings.Cut(rule, "=")
+		if name != "" {
+			rules = append(rules, tagRule{name: name, param: param})
+		}
+	}
+	return rules
+}
+
+// fieldName returns the name a field goes by in forms and JSON
+func fieldName(field reflect.StructField) string {
+	for _, tag := range []string{"form", "json"} {
+		if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" {
+			return name
+		}
+	}
+	return field.Name
+}
+
+// absent reports whether a value was left out: a nil pointer, or an empty string, slice or map
+func absent(value reflect.Value) bool {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		return value.IsNil()
+	case reflect.String:
+		return strings.TrimSpace(value.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return value.Len() == 0
+	}
+	return false
+}
+
+// size returns what min and max compare: the length of strings, slices and maps, and the
+// value of numbers
+func size(value reflect.Value) (float64, string, bool) {
+	value = reflect.Indirect(value)
+	switch value.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(value.String())), "length", true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(value.Len()), "items", true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int()), "number", true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(value.Uint()), "number", true
+	case reflect.Float32, reflect.Float64:
+		return value.Float(), "number", true
+	}
+	return 0, "", false
+}
+
+// stringValue returns the value of a field as a string
+func stringValue(value reflect.Value) string {
+	value = reflect.Indirect(value)
+	if !value.IsValid() {
+		return ""
+	}
+	if value.Kind() == reflect.String {
+		return value.String()
+	}
+	return fmt.Sprint(value.Interface())
+}
+
+func numberParam(rule, param string) (float64, error) {
+	n, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s needs a number, got %q", ErrValidationRule, rule, param)
+	}
+	return n, nil
+}
+
+// ruleRequired fails for absent values and for zero values other than pointers, so 0 and false
+// fail too. Fields that accept them are pointers, such as *int or *bool, which pass when set
+func ruleRequired(v *Validation, value reflect.Value, _ string, _ reflect.Value) (string, error) {
+	if absent(value) || (value.Kind() != reflect.Ptr && value.IsZero()) {
+		return v.message("validation.required", "This field is required"), nil
+	}
+	return "", nil
+}
+
+func ruleEmail(v *Validation, value reflect.Value, _ string, _ reflect.Value) (string, error) {
+	if !govalidator.IsEmail(stringValue(value)) {
+		return v.message("validation.email", "Invalid email address"), nil
+	}
+	return "", nil
+}
+
+func ruleMin(v *Validation, value reflect.Value, param string, _ reflect.Value) (string, error) {
+	min, err := numberParam("min", param)
+	if err != nil {
+		return "", err
+	}
+	n, kind, ok := size(value)
+	if !ok || n >= min {
+		return "", nil
+	}
+
+	switch kind {
+	case "length":
+		return v.message("validation.min_length", fmt.Sprintf("This field must be at least %s characters long", param), "min", param), nil
+	case "items":
+		return v.message("validation.min_items", fmt.Sprintf("This field must have at least %s items", param), "min", param), nil
+	default:
+		return v.message("validation.min", fmt.Sprintf("This field must be at least %s", param), "min", param), nil
+	}
+}
+
+func ruleMax(v *Validation, value reflect.Value, param string, _ reflect.Value) (string, error) {
+	max, err := numberParam("max", param)
+	if err != nil {
+		return "", err
+	}
+	n, kind, ok := size(value)
+	if !ok || n <= max {
+		return "", nil
+	}
+
+	switch kind {
+	case "length":
+		return v.message("validation.max_length", fmt.Sprintf("This field must be at most %s characters long", param), "max", param), nil
+	case "items":
+		return v.message("validation.max_items", fmt.Sprintf("This field must have at most %s items", param), "max", param), nil
+	default:
+		return v.message("validation.max", fmt.Sprintf("This field must be at most %s", param), "max", param), nil
+	}
+}
+
+func ruleOneOf(v *Validation, value reflect.Value, param string, _ reflect.Value) (string, error) {
+	options := strings.Fields(param)
+	s := stringValue(value)
+	for _, option := range options {
+		if s == option {
+			return "", nil
+		}
+	}
+	values := strings.Join(options, ", ")
+	return v.message("validation.oneof", fmt.Sprintf("This field must be one of: %s", values), "values", values), nil
+}
+
+func ruleNotIn(v *Validation, value reflect.Value, param string, _ reflect.Value) (string, error) {
+	if inSlice(strings.Fields(param), stringValue(value)) {
+		return v.message("validation.notin", "This value is not allowed"), nil
+	}
+	return "", nil
+}
+
+// patterns caches the compiled patterns of regex rules
+var patterns sync.Map
+
+func ruleRegex(v *Validation, value reflect.Value, param string, _ reflect.Value) (string, error) {
+	pattern, ok := patterns.Load(param)
+	if !ok {
+		compiled, err := regexp.Compile(param)
+		if err != nil {
+			return "", fmt.Errorf("%w: regex: %w", ErrValidationRule, err)
+		}
+		pattern, _ = patterns.LoadOrStore(param, compiled)
+	}
+	if !pattern.(*regexp.Regexp).MatchString(stringValue(value)) {
+		return v.message("validation.regex", "This field has an invalid format"), nil
+	}
+	return "", nil
+}
+
+func ruleURL(v *Validation, value reflect.Value, _ string, _ reflect.Value) (string, error) {
+	if !isURL(stringValue(value)) {
+		return v.message("validation.url", "This field must be a valid URL"), nil
+	}
+	return "", nil
+}
+
+func ruleUUID(v *Validation, value reflect.Value, _ string, _ reflect.Value) (string, error) {
+	if !govalidator.IsUUID(stringValue(value)) {
+		return v.message("validation.uuid", "This field must be a valid UUID"), nil
+	}
+	return "", nil
+}
+
+func ruleIP(v *Validation, value reflect.Value, _ string, _ reflect.Value) (string, error) {
+	if net.ParseIP(stringValue(value)) == nil {
+		return v.message("validation.ip", "This field must be a valid IP address"), nil
+	}
+	return "", nil
+}
+
+func ruleAlphaNumeric(v *Validation, value reflect.Value, _ string, _ reflect.Value) (string, error) {
+	if !govalidator.IsAlphanumeric(stringValue(value)) {
+		return v.message("validation.alphanum", "This field may only contain letters and numbers"), nil
+	}
+	return "", nil
+}
+
+func ruleSame(v *Validation, value reflect.Value, param string, parent reflect.Value) (string, error) {
+	other, name, ok := siblingField(parent, param)
+	if !ok {
+		return "", fmt.Errorf("%w: same refers to unknown field %q", ErrValidationRule, param)
+	}
+	if !other.CanInterface() {
+		return "", fmt.Errorf("%w: same refers to unexported field %q", ErrValidationRule, param)
+	}
+	if !reflect.DeepEqual(value.Interface(), other.Interface()) {
+		return v.message("validation.same", "This field must match "+name, "other", name), nil
+	}
+	return "", nil
+}
+
+func ruleConfirmed(v *Validation, value reflect.Value, param string, parent reflect.Value) (string, error) {
+	other, _, ok := siblingField(parent, param)
+	if !ok {
+		return "", fmt.Errorf("%w: confirmed needs a field called %s", ErrValidationRule, param)
+	}
+	if !other.CanInterface() {
+		return "", fmt.Errorf("%w: confirmed needs %s to be exported", ErrValidationRule, param)
+	}
+	if !reflect.DeepEqual(value.Interface(), other.Interface()) {
+		return v.message("validation.confirmed", "The confirmation does not match"), nil
+	}
+	return "", nil
+}
+
+func rulePassword(v *Validation, value reflect.Value, param string, _ reflect.Value) (string, error) {
+	minLength := 8
+	if param != "" {
+		n, err := numberParam("password", param)
+		if err != nil {
+			return "", err
+		}
+		minLength = int(n)
+	}
+	if !isStrongPassword(stringValue(value), minLength) {
+		return v.message("validation.password",
+			fmt.Sprintf("The password must be at least %d characters long and contain upper and lower case letters, numbers and symbols", minLength),
+			"min", minLength), nil
+	}
+	return "", nil
+}
+
+func ruleDate(v *Validation, value reflect.Value, param string, _ reflect.Value) (string, error) {
+	layouts := TimeLayouts
+	if param != "" {
+		layouts = []string{param}
+	}
+	if reflect.Indirect(value).Type() == timeType {
+		return "", nil
+	}
+	if _, ok := parseTime(stringValue(value), layouts); !ok {
+		return v.message("validation.date", "This field must be a date in the format "+dateFormat(layouts[0]), "format", dateFormat(layouts[0])), nil
+	}
+	return "", nil
+}
+
+func ruleBefore(v *Validation, value reflect.Value, param string, _ reflect.Value) (string, error) {
+	date, limit, ok, err := compareDates("before", value, param)
+	if err != nil {
+		return "", err
+	}
+	if ok && !date.Before(limit) {
+		return v.message("validation.before", "This field must be a date before "+param, "date", param), nil
+	}
+	return "", nil
+}
+
+func ruleAfter(v *Validation, value reflect.Value, param string, _ reflect.Value) (string, error) {
+	date, limit, ok, err := compareDates("after", value, param)
+	if err != nil {
+		return "", err
+	}
+	if ok && !date.After(limit) {
+		return v.message("validation.after", "This field must be a date after "+param, "date", param), nil
+	}
+	return "", nil
+}
+
+func ruleUnique(v *Validation, value reflect.Value, param string, _ reflect.Value) (string, error) {
+	table, column, err := tableColumn("unique", param)
+	if err != nil {
+		return "", err
+	}
+	count, err := v.count(table, column, stringValue(value))
+	if err != nil {
+		return "", err
+	}
+	if count > 0 {
+		return v.message("validation.unique", "This value has already been taken"), nil
+	}
+	return "", nil
+}
+
+func ruleExists(v *Validation, value reflect.Value, param string, _ reflect.Value) (string, error) {
+	table, column, err := tableColumn("exists", param)
+	if err != nil {
+		return "", err
+	}
+	count, err := v.count(table, column, stringValue(value))
+	if err != nil {
+		return "", err
+	}
+	if count == 0 {
+		return v.message("validation.exists", "The selected value is invalid"), nil
+	}
+	return "", nil
+}
+
+// siblingField returns the field of parent called name, matching either its Go name or the name
+// it goes by in forms and JSON, along with the latter
+func siblingField(parent reflect.Value, name string) (reflect.Value, string, bool) {
+	t := parent.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Name == name || fieldName(field) == name {
+			return parent.Field(i), fieldName(field), true
+		}
+	}
+	return reflect.Value{}, "", false
+}
+
+// compareDates returns the date held by value and the date named by param, a 2006-01-02 date
+// or now. It reports false when value isn't a date, which the date rule reports on its own
+func compareDates(rule string, value reflect.Value, param string) (time.Time, time.Time, bool, error) {
+	var limit time.Time
+	if param == "now" {
+		limit = time.Now()
+	} else {
+		var ok bool
+		limit, ok = parseTime(param, TimeLayouts)
+		if !ok {
+			return time.Time{}, time.Time{}, false, fmt.Errorf("%w: %s needs a date or now, got %q", ErrValidationRule, rule, param)
+		}
+	}
+
+	value = reflect.Indirect(value)
+	if value.Type() == timeType {
+		return value.Interface().(time.Time), limit, true, nil
+	}
+	date, ok := parseTime(stringValue(value), TimeLayouts)
+	return date, limit, ok, nil
+}
+
+func tableColumn(rule, param string) (string, string, error) {
+	i := strings.LastIndex(param, ".")
+	if i <= 0 {
+		return "", "", fmt.Errorf("%w: %s needs a table.column, got %q", ErrValidationRule, rule, param)
+	}
+	return param[:i], param[i+1:], nil
+}
@@ -0,0 +1,260 @@
+package velox
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag      string
+		expected []tagRule
+	}{
+		{"", nil},
+		{"required", []tagRule{{"required", ""}}},
+		{"required,min=3, max=10", []tagRule{{"required", ""}, {"min", "3"}, {"max", "10"}}},
+		{"oneof=a b c", []tagRule{{"oneof", "a b c"}}},
+		{"required,,email", []tagRule{{"required", ""}, {"email", ""}}},
+		{`regex=^\d{2,4}$`, []tagRule{{"regex", `^\d{2,4}$`}}},
+		{`required,regex=^[a,b]=$,min=2`, []tagRule{{"required", ""}, {"regex", `^[a,b]=$,min=2`}}},
+		{"before=now", []tagRule{{"before", "now"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.tag, func(t *testing.T) {
+			if got := parseTag(tt.tag); !reflect.DeepEqual(got, tt.expected) {
+				t.Errorf("expected %v, got %v", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestValidation_Struct(t *testing.T) {
+	type Address struct {
+		City string `form:"city" validate:"required"`
+	}
+
+	tomorrow := time.Now().Add(24 * time.Hour)
+	ptr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name    string
+		data    interface{}
+		invalid []string
+	}{
+		{"required", &struct {
+			Name string `json:"name" validate:"required"`
+		}{}, []string{"name"}},
+		{"required blank", struct {
+			Name string `json:"name" validate:"required"`
+		}{"  "}, []string{"name"}},
+		{"required number", struct {
+			Age int `json:"age" validate:"required"`
+		}{}, []string{"age"}},
+		{"required pointer", struct {
+			Nick *string `json:"nick" validate:"required"`
+		}{ptr("")}, nil},
+		{"required zero values", struct {
+			Age    int   `json:"age" validate:"required"`
+			Agreed bool  `json:"agreed" validate:"required"`
+			Count  *int  `json:"count" validate:"required"`
+			Opted  *bool `json:"opted" validate:"required"`
+		}{Count: new(int), Opted: new(bool)}, []string{"age", "agreed"}},
+		{"optional skips rules", struct {
+			Email string `json:"email" validate:"email,min=3"`
+		}{}, nil},
+		{"email", struct {
+			Email string `json:"email" validate:"email"`
+			Other string `json:"other" validate:"email"`
+		}{"me@here.com", "nope"}, []string{"other"}},
+		{"min and max length", struct {
+			Short string `json:"short" validate:"min=3"`
+			Long  string `json:"long" validate:"max=3"`
+			Fits  string `json:"fits" validate:"min=2,max=3"`
+		}{"ab", "abcd", "çãó"}, []string{"long", "short"}},
+		{"min and max value", struct {
+			Low  int     `json:"low" validate:"min=18"`
+			High float64 `json:"high" validate:"max=1.5"`
+			Fits uint    `json:"fits" validate:"min=1,max=9"`
+		}{17, 1.6, 9}, []string{"high", "low"}},
+		{"min and max items", struct {
+			Few  []string `json:"few" validate:"min=2"`
+			Many []string `json:"many" validate:"max=1"`
+		}{[]string{"a"}, []string{"a", "b"}}, []string{"few", "many"}},
+		{"oneof and in", struct {
+			Role  string `json:"role" validate:"oneof=admin editor"`
+			Other string `json:"other" validate:"in=admin editor"`
+		}{"admin", "viewer"}, []string{"other"}},
+		{"notin", struct {
+			Name  string `json:"name" validate:"notin=root admin"`
+			Other string `json:"other" validate:"notin=root admin"`
+		}{"ana", "root"}, []string{"other"}},
+		{"regex", struct {
+			Year  string `json:"year" validate:"regex=^\\d{2,4}$"`
+			Other string `json:"other" validate:"regex=^\\d{2,4}$"`
+		}{"2024", "20245"}, []string{"other"}},
+		{"url", struct {
+			Site  string `json:"site" validate:"url"`
+			Other string `json:"other" validate:"url"`
+		}{"https://example.com", "example.com"}, []string{"other"}},
+		{"uuid", struct {
+			ID    string `json:"id" validate:"uuid"`
+			Other string `json:"other" validate:"uuid"`
+		}{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", "123"}, []string{"other"}},
+		{"ip", struct {
+			IP    string `json:"ip" validate:"ip"`
+			Other string `json:"other" validate:"ip"`
+		}{"::1", "300.1.1.1"}, []string{"other"}},
+		{"alphanum", struct {
+			Code  string `json:"code" validate:"alphanum"`
+			Other string `json:"other" validate:"alphanum"`
+		}{"abc123", "abc-123"}, []string{"other"}},
+		{"same", struct {
+			Email       string `json:"email"`
+			RepeatEmail string `json:"repeat_email" validate:"same=email"`
+		}{"a@b.c", "b@b.c"}, []string{"repeat_email"}},
+		{"confirmed by go name", struct {
+			Password             string `json:"password" validate:"confirmed"`
+			PasswordConfirmation string `json:"password_confirmation"`
+		}{"secret", "secret"}, nil},
+		{"confirmed by field name", struct {
+			Password string `form:"password" validate:"confirmed"`
+			Repeat   string `form:"password_confirmation"`
+		}{"secret", "other"}, []string{"password"}},
+		{"password", struct {
+			Strong string `json:"strong" validate:"password"`
+			Weak   string `json:"weak" validate:"password=12"`
+		}{"Secret-123", "Secret-123"}, []string{"weak"}},
+		{"date", struct {
+			Day    string    `json:"day" validate:"date"`
+			Custom string    `json:"custom" validate:"date=02/01/2006"`
+			Time   time.Time `json:"time" validate:"date"`
+			Other  string    `json:"other" validate:"date"`
+		}{"2024-02-29", "29/02/2024", time.Now(), "yesterday"}, []string{"other"}},
+		{"before and after", struct {
+			Past   string    `json:"past" validate:"before=now"`
+			Future time.Time `json:"future" validate:"after=now"`
+			Early  string    `json:"early" validate:"after=2024-01-01"`
+			Late   string    `json:"late" validate:"before=2024-01-01"`
+		}{"2020-01-01", tomorrow, "2023-12-31", "2024-06-01"}, []string{"early", "late"}},
+		{"first failing rule wins", struct {
+			Name string `json:"name" validate:"min=5,email"`
+		}{"ab"}, []string{"name"}},
+		{"nested", struct {
+			Address  Address   `json:"address"`
+			Optional *Address  `json:"optional"`
+			Items    []Address `json:"items"`
+		}{Items: []Address{{"Porto"}, {}}}, []string{"address.city", "items.1.city"}},
+		{"skipped fields", struct {
+			Hidden string `json:"-" validate:"required"`
+			Empty  string `json:"empty" validate:"-"`
+			secret string `validate:"required"`
+		}{}, nil},
+	}
+
+	v := newTestVelox()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validation := v.Validator(nil)
+			if err := validation.Struct(tt.data); err != nil {
+				t.Fatal(err)
+			}
+
+			var invalid []string
+			for key := range validation.Errors {
+				invalid = append(invalid, key)
+			}
+			sort.Strings(invalid)
+			if !reflect.DeepEqual(invalid, tt.invalid) {
+				t.Errorf("expected errors for %v, got %v", tt.invalid, validation.Errors)
+			}
+		})
+	}
+}
+
+func TestValidation_StructErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data interface{}
+		rule bool
+	}{
+		{"not a struct", map[string]string{}, false},
+		{"nil", nil, false},
+		{"unknown rule", struct {
+			Name string `validate:"required,shiny"`
+		}{"ana"}, true},
+		{"min without a number", struct {
+			Name string `validate:"min=three"`
+		}{"ana"}, true},
+		{"password without a number", struct {
+			Password string `validate:"password=long"`
+		}{"Secret-123"}, true},
+		{"invalid regex", struct {
+			Code string `validate:"regex=[a-"`
+		}{"a"}, true},
+		{"same without the field", struct {
+			Email string `validate:"same=Other"`
+		}{"a@b.c"}, true},
+		{"confirmed without the field", struct {
+			Password string `validate:"confirmed"`
+		}{"secret"}, true},
+		{"same as an unexported field", struct {
+			Email string `validate:"same=other"`
+			other string
+		}{"a@b.c", "a@b.c"}, true},
+		{"confirmed by an unexported field", struct {
+			Password string `validate:"confirmed=repeat"`
+			repeat   string
+		}{"secret", "secret"}, true},
+		{"before without a date", struct {
+			Day string `validate:"before=soon"`
+		}{"2024-01-01"}, true},
+		{"unique without a column", struct {
+			Email string `validate:"unique=users"`
+		}{"a@b.c"}, true},
+		{"unique without a database", struct {
+			Email string `validate:"unique=users.email"`
+		}{"a@b.c"}, false},
+		{"exists without a database", struct {
+			UserID string `validate:"exists=users.id"`
+		}{"1"}, false},
+	}
+
+	v := newTestVelox()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			validation := v.Validator(nil)
+			err := validation.Struct(tt.data)
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if errors.Is(err, ErrValidationRule) != tt.rule {
+				t.Errorf("expected wrapping ErrValidationRule to be %t, got %v", tt.rule, err)
+			}
+			if !validation.Valid() {
+				t.Errorf("expected no validation errors, got %v", validation.Errors)
+			}
+		})
+	}
+}
+
+func TestValidation_StructErrorKeepsChecking(t *testing.T) {
+	data := struct {
+		Email string `json:"email" validate:"unique=users.email"`
+		Name  string `json:"name" validate:"required"`
+	}{Email: "a@b.c"}
+
+	validation := newTestVelox().Validator(nil)
+	if err := validation.Struct(&data); err == nil {
+		t.Error("expected the database error to be returned")
+	}
+	if validation.Errors["name"] == "" {
+		t.Error("expected the other fields to be checked")
+	}
+	if _, ok := validation.Errors["email"]; ok {
+		t.Error("a database error should not be reported as a validation error")
+	}
+}
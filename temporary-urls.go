@@ -0,0 +1,216 @@
+package velox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+	"github.com/FernandoJVideira/velox/urlsigner"
+	"github.com/go-chi/chi/v5"
+)
+
+// ErrFileRoutesNotMounted is returned for temporary links to disks that can't make them themselves,
+// until the routes serving them are mounted with MountFileRoutes
+var ErrFileRoutesNotMounted = errors.New("velox: file routes are not mounted")
+
+// TemporaryURL returns a link to download the file at key on disk, which works for expiry. Disks
+// that can't make links, such as SFTP, WebDAV and local ones, get a signed link to the routes
+// mounted with MountFileRoutes, which serve the file
+func (v *Velox) TemporaryURL(disk, key string, expiry time.Duration) (string, error) {
+	fs, err := v.Disks.Get(disk)
+	if err != nil {
+		return "", err
+	}
+	if presigner, ok := fs.(filesystems.Presigner); ok {
+		return presigner.TemporaryURL(key, expiry)
+	}
+
+	return v.signFileURL("download", disk, url.Values{"key": {key}}, expiry)
+}
+
+// TemporaryUploadURL returns a link to upload a file to key on disk with a PUT request, which works
+// for expiry. When contentType is set, the request has to send it as its Content-Type. As with
+// TemporaryURL, disks that can't make links get a signed link to the application
+func (v *Velox) TemporaryUploadURL(disk, key string, expiry time.Duration, contentType string) (string, error) {
+	fs, err := v.Disks.Get(disk)
+	if err != nil {
+		return "", err
+	}
+	if presigner, ok := fs.(filesystems.Presigner); ok {
+		return presigner.TemporaryUploadURL(key, expiry, contentType)
+	}
+
+	values := url.Values{"key": {key}}
+	if contentType != "" {
+		values.Set("content_type", contentType)
+	}
+	return v.signFileURL("upload", disk, values, expiry)
+}
+
+// MountFileRoutes mounts the routes temporary links to files point to at pattern, e.g. /files, and
+// exempts them from CSRF checks, since the links are signed instead. Uploads through them are limited
+// to MAX_UPLOAD_SIZE
+func (v *Velox) MountFileRoutes(pattern string) {
+	pattern = "/" + strings.Trim(pattern, "/")
+	v.fileRoutes = pattern
+	v.csrfExempt = append(v.csrfExempt, pattern)
+
+	mux := chi.NewRouter()
+	mux.Get("/download/{disk}", v.serveFile)
+	mux.Head("/download/{disk}", v.serveFile)
+	mux.Put("/upload/{disk}", v.receiveFile)
+	v.Routes.Mount(pattern, mux)
+}
+
+// signFileURL returns a signed link to the file routes, which expires after expiry
+func (v *Velox) signFileURL(action, disk string, values url.Values, expiry time.Duration) (string, error) {
+	if v.fileRoutes == "" {
+		return "", ErrFileRoutesNotMounted
+	}
+
+	values.Set("expires", strconv.FormatInt(time.Now().Add(expiry).Unix(), 10))
+	link := fmt.Sprintf("%s%s/%s/%s?%s", v.Server.URL, v.fileRoutes, action, url.PathEscape(disk), values.Encode())
+
+	signer := urlsigner.Signer{
+		Secret: []byte(v.EncryptionKey),
+	}
+	return signer.GenereateTokenFromString(link), nil
+}
+
+// verifyFileURL reports whether the request was made with a signed link that hasn't expired
+func (v *Velox) verifyFileURL(r *http.Request) bool {
+	signer := urlsigner.Signer{
+		Secret: []byte(v.EncryptionKey),
+	}
+	if !signer.VerifyToken(v.Server.URL + r.RequestURI) {
+		return false
+	}
+
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	return err == nil && time.Now().Unix() <= expires
+}
+
+// serveFile sends the file a signed download link points to
+func (v *Velox) serveFile(w http.ResponseWriter, r *http.Request) {
+	if !v.verifyFileURL(r) {
+		v.ErrorForbidden(w, r)
+		return
+	}
+
+	fs, err := v.Disks.Get(chi.URLParam(r, "disk"))
+	if err != nil {
+		v.Error404(w, r)
+		return
+	}
+
+	key := r.URL.Query().Get("key")
+	info, err := fs.Stat(r.Context(), key)
+	if err != nil {
+		v.fileError(w, r, err)
+		return
+	}
+
+	file, err := fs.Open(r.Context(), key)
+	if err != nil {
+		v.fileError(w, r, err)
+		return
+	}
+	defer file.Close()
+
+	contentType := info.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size, 10))
+	w.Header().Set("Cache-Control", "private, no-store")
+	if !info.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", info.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, err = io.Copy(w, file)
+	if err != nil {
+		v.ErrorLog.Println(err)
+	}
+}
+
+// receiveFile stores the file sent to a signed upload link. As with UploadFile, it has to be of
+// a type in ALLOWED_FILETYPES, is scanned by v.UploadScanner, and is stored with the type found in
+// its data rather than the one the client sent
+func (v *Velox) receiveFile(w http.ResponseWriter, r *http.Request) {
+	if !v.verifyFileURL(r) {
+		v.ErrorForbidden(w, r)
+		return
+	}
+
+	fs, err := v.Disks.Get(chi.URLParam(r, "disk"))
+	if err != nil {
+		v.Error404(w, r)
+		return
+	}
+
+	contentType := r.URL.Query().Get("content_type")
+	if contentType != "" && r.Header.Get("Content-Type") != contentType {
+		v.ErrorStatus(w, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	mimeType, body, err := detectType(http.MaxBytesReader(w, r.Body, v.config.uploads.maxUploadSize))
+	if err != nil {
+		v.fileError(w, r, err)
+		return
+	}
+	if !allowedMimeType(mimeType, v.config.uploads.allowedMimeTypes) {
+		v.fileError(w, r, fmt.Errorf("%w: %s", ErrUploadType, mimeType.String()))
+		return
+	}
+
+	if v.UploadScanner != nil {
+		scanned, err := v.scanUpload(r.Context(), body, v.UploadScanner)
+		if err != nil {
+			v.fileError(w, r, err)
+			return
+		}
+		defer scanned.Close()
+		body = scanned
+	}
+
+	err = fs.PutStream(r.Context(), r.URL.Query().Get("key"), body, filesystems.PutOptions{
+		ContentType: mimeType.String(),
+		Size:        r.ContentLength,
+	})
+	if err != nil {
+		v.fileError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// fileError responds to an error reading or writing a file through the file routes
+func (v *Velox) fileError(w http.ResponseWriter, r *http.Request, err error) {
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.As(err, &maxBytesErr):
+		v.ErrorStatus(w, http.StatusRequestEntityTooLarge)
+	case errors.Is(err, ErrUploadType):
+		v.ErrorStatus(w, http.StatusUnsupportedMediaType)
+	case errors.Is(err, ErrUploadInfected):
+		v.ErrorStatus(w, http.StatusUnprocessableEntity)
+	case errors.Is(err, os.ErrNotExist):
+		v.Error404(w, r)
+	default:
+		v.ErrorLog.Println(err)
+		v.Error500(w, r)
+	}
+}
@@ -0,0 +1,71 @@
+package velox
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+	"github.com/FernandoJVideira/velox/filesystems/memfilesystem"
+	"github.com/go-chi/chi/v5"
+)
+
+func TestTemporaryUploadURL_Checks(t *testing.T) {
+	fs := &memfilesystem.Mem{}
+
+	v := newTestVelox()
+	v.RootPath = t.TempDir()
+	v.Routes = chi.NewRouter()
+	v.Disks = &filesystems.Disks{}
+	v.Disks.Add("files", fs)
+	v.EncryptionKey = "0123456789abcdef0123456789abcdef"
+	v.Server.URL = "http://localhost:4000"
+	v.config.uploads.maxUploadSize = 64
+	v.config.uploads.allowedMimeTypes = []string{"text/plain"}
+	v.UploadScanner = signatureScanner{"EICAR"}
+	v.MountFileRoutes("/files")
+
+	tests := []struct {
+		name   string
+		key    string
+		body   string
+		status int
+	}{
+		{"allowed", "notes.txt", "some notes", http.StatusNoContent},
+		{"wrong type", "report.pdf", "%PDF-1.4 not notes", http.StatusUnsupportedMediaType},
+		{"infected", "virus.txt", "notes with EICAR in them", http.StatusUnprocessableEntity},
+		{"too large", "large.txt", strings.Repeat("notes ", 20), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			link, err := v.TemporaryUploadURL("files", tt.key, time.Minute, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := url.Parse(link)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest("PUT", u.RequestURI(), strings.NewReader(tt.body))
+			r.Header.Set("Content-Type", "text/html")
+			w := httptest.NewRecorder()
+			v.Routes.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if keys := fs.Keys(); len(keys) != 1 || string(fs.Content("notes.txt")) != "some notes" {
+		t.Errorf("expected only the allowed upload to be stored, got %v", keys)
+	}
+	if file, _ := fs.File("notes.txt"); file.ContentType != "text/plain; charset=utf-8" {
+		t.Errorf("expected the upload to be stored with the type found in it, got %q", file.ContentType)
+	}
+}
@@ -0,0 +1,91 @@
+package velox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+	"github.com/FernandoJVideira/velox/tus"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// NewTusHandler returns a tus resumable upload server that stores finished uploads on fs under
+// destination. Partial uploads are kept in tmp/tus, or in Badger when TUS_STORE is badger and
+// Badger is the cache, and the expired ones are removed every hour. Uploads are limited to
+// MAX_UPLOAD_SIZE; change the handler's MaxSize to allow larger ones. As with UploadFile, finished
+// uploads are checked against ALLOWED_FILETYPES and scanned by v.UploadScanner before they are stored
+func (v *Velox) NewTusHandler(fs filesystems.FS, destination string) *tus.Handler {
+	var store tus.Store = &tus.FileStore{Dir: filepath.Join(v.RootPath, "tmp", "tus")}
+	if strings.ToLower(os.Getenv("TUS_STORE")) == "badger" && badgerConn != nil {
+		store = &tus.BadgerStore{Conn: badgerConn, Prefix: v.AppName + ":"}
+	}
+
+	handler := &tus.Handler{
+		Store:       store,
+		FS:          fs,
+		Destination: destination,
+		MaxSize:     v.config.uploads.maxUploadSize,
+		Validate:    v.validateTusUpload,
+		ErrorLog:    v.ErrorLog,
+	}
+
+	if v.Scheduler != nil {
+		_, err := v.Scheduler.AddFunc("@hourly", func() {
+			if err := handler.Cleanup(); err != nil {
+				v.ErrorLog.Println(err)
+			}
+		})
+		if err != nil {
+			v.ErrorLog.Println(err)
+		}
+	}
+
+	return handler
+}
+
+// validateTusUpload refuses the finished tus uploads storeUpload would refuse: those of a type
+// that isn't allowed, and infected ones. The type is the one the handler found in the data, which
+// the file is stored with
+func (v *Velox) validateTusUpload(ctx context.Context, upload tus.Upload, src io.Reader) error {
+	mediaType, _, _ := mime.ParseMediaType(upload.ContentType)
+	mimeType := mimetype.Lookup(mediaType)
+	if mimeType == nil || !allowedMimeType(mimeType, v.config.uploads.allowedMimeTypes) {
+		return fmt.Errorf("%w: %w: %s", tus.ErrRejected, ErrUploadType, upload.ContentType)
+	}
+
+	if v.UploadScanner == nil {
+		return nil
+	}
+	signature, err := v.UploadScanner.Scan(ctx, src)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUploadScan, err)
+	}
+	if signature != "" {
+		return fmt.Errorf("%w: %w", tus.ErrRejected, &UploadInfectedError{Signature: signature})
+	}
+	return nil
+}
+
+// MountTus mounts a tus handler at pattern, e.g. /uploads, and exempts it from CSRF checks, since
+// tus clients can't send the token. Wrap the handler in middleware to require authentication
+func (v *Velox) MountTus(pattern string, handler http.Handler) {
+	pattern = "/" + strings.Trim(pattern, "/")
+	v.csrfExempt = append(v.csrfExempt, pattern)
+	v.Routes.Mount(pattern, handler)
+}
+
+// isCSRFExempt reports whether the request is for a path mounted without CSRF checks
+func (v *Velox) isCSRFExempt(r *http.Request) bool {
+	for _, pattern := range v.csrfExempt {
+		if r.URL.Path == pattern || strings.HasPrefix(r.URL.Path, pattern+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,203 @@
+package tus
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+// chunkSize is the largest piece of data kept under a single Badger key
+const chunkSize = 1 << 20
+
+// BadgerStore keeps uploads in a Badger database, such as the one used for the cache. The data
+// of each upload is split into chunks of at most 1MB, keyed by their offset
+type BadgerStore struct {
+	Conn   *badger.DB
+	Prefix string
+}
+
+func (b *BadgerStore) Create(upload Upload) error {
+	return b.Update(upload)
+}
+
+func (b *BadgerStore) Get(id string) (Upload, error) {
+	var upload Upload
+	if !validID.MatchString(id) {
+		return upload, ErrNotFound
+	}
+
+	err := b.Conn.View(func(txn *badger.Txn) error {
+		item, err := txn.Get(b.infoKey(id))
+		if err != nil {
+			return err
+		}
+		return item.Value(func(val []byte) error {
+			return json.Unmarshal(val, &upload)
+		})
+	})
+	if errors.Is(err, badger.ErrKeyNotFound) {
+		return upload, ErrNotFound
+	}
+	return upload, err
+}
+
+func (b *BadgerStore) Update(upload Upload) error {
+	content, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	return b.Conn.Update(func(txn *badger.Txn) error {
+		return txn.Set(b.infoKey(upload.ID), content)
+	})
+}
+
+func (b *BadgerStore) WriteChunk(id string, offset int64, r io.Reader) (int64, error) {
+	// a previous request may have written data after offset before failing; it is overwritten
+	err := b.deletePrefix(b.dataPrefix(id), offset)
+	if err != nil {
+		return 0, err
+	}
+
+	var written int64
+	buf := make([]byte, chunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			chunk := append([]byte{}, buf[:n]...)
+			key := b.chunkKey(id, offset+written)
+			err := b.Conn.Update(func(txn *badger.Txn) error {
+				return txn.Set(key, chunk)
+			})
+			if err != nil {
+				return written, err
+			}
+			written += int64(n)
+		}
+
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			return written, nil
+		}
+		if readErr != nil {
+			return written, readErr
+		}
+	}
+}
+
+func (b *BadgerStore) Reader(id string) (io.ReadCloser, error) {
+	if _, err := b.Get(id); err != nil {
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		err := b.Conn.View(func(txn *badger.Txn) error {
+			it := txn.NewIterator(badger.DefaultIteratorOptions)
+			defer it.Close()
+
+			prefix := b.dataPrefix(id)
+			for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+				err := it.Item().Value(func(val []byte) error {
+					_, err := pw.Write(val)
+					return err
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		pw.CloseWithError(err)
+	}()
+
+	return pr, nil
+}
+
+func (b *BadgerStore) DeleteData(id string) error {
+	return b.deletePrefix(b.dataPrefix(id), 0)
+}
+
+func (b *BadgerStore) Delete(id string) error {
+	if _, err := b.Get(id); err != nil {
+		return err
+	}
+	err := b.DeleteData(id)
+	if err != nil {
+		return err
+	}
+	return b.Conn.Update(func(txn *badger.Txn) error {
+		return txn.Delete(b.infoKey(id))
+	})
+}
+
+func (b *BadgerStore) List() ([]Upload, error) {
+	var uploads []Upload
+
+	err := b.Conn.View(func(txn *badger.Txn) error {
+		it := txn.NewIterator(badger.DefaultIteratorOptions)
+		defer it.Close()
+
+		prefix := []byte(b.Prefix + "tus:info:")
+		for it.Seek(prefix); it.ValidForPrefix(prefix); it.Next() {
+			var upload Upload
+			err := it.Item().Value(func(val []byte) error {
+				return json.Unmarshal(val, &upload)
+			})
+			if err != nil {
+				return err
+			}
+			uploads = append(uploads, upload)
+		}
+		return nil
+	})
+
+	return uploads, err
+}
+
+// deletePrefix removes the data chunks under prefix that start at or after offset
+func (b *BadgerStore) deletePrefix(prefix []byte, offset int64) error {
+	var keys [][]byte
+	from := append(append([]byte{}, prefix...), []byte(fmt.Sprintf("%020d", offset))...)
+
+	err := b.Conn.View(func(txn *badger.Txn) error {
+		opts := badger.DefaultIteratorOptions
+		opts.PrefetchValues = false
+		it := txn.NewIterator(opts)
+		defer it.Close()
+
+		for it.Seek(from); it.ValidForPrefix(prefix); it.Next() {
+			keys = append(keys, it.Item().KeyCopy(nil))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	batch := b.Conn.NewWriteBatch()
+	defer batch.Cancel()
+	for _, key := range keys {
+		if err := batch.Delete(key); err != nil {
+			return err
+		}
+	}
+	return batch.Flush()
+}
+
+func (b *BadgerStore) infoKey(id string) []byte {
+	return []byte(b.Prefix + "tus:info:" + id)
+}
+
+func (b *BadgerStore) dataPrefix(id string) []byte {
+	return []byte(b.Prefix + "tus:data:" + id + ":")
+}
+
+// chunkKey is the key of the chunk starting at offset; offsets are zero padded so that keys sort
+// in the order of the data
+func (b *BadgerStore) chunkKey(id string, offset int64) []byte {
+	return bytes.Join([][]byte{b.dataPrefix(id), []byte(fmt.Sprintf("%020d", offset))}, nil)
+}
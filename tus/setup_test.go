@@ -0,0 +1,32 @@
+package tus
+
+import (
+	"log"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/dgraph-io/badger/v3"
+)
+
+var testDir string
+var testBadger *badger.DB
+
+func TestMain(m *testing.M) {
+	var err error
+	testDir, err = os.MkdirTemp("", "velox-tus")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	testBadger, err = badger.Open(badger.DefaultOptions(path.Join(testDir, "badger")).WithLogger(nil))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code := m.Run()
+
+	_ = testBadger.Close()
+	_ = os.RemoveAll(testDir)
+	os.Exit(code)
+}
@@ -0,0 +1,188 @@
+package tus
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by stores for uploads they don't have
+var ErrNotFound = errors.New("tus: upload not found")
+
+// Upload describes an upload in progress, or a finished one
+type Upload struct {
+	ID       string            `json:"id"`
+	Length   int64             `json:"length"`
+	Offset   int64             `json:"offset"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	Expires  time.Time         `json:"expires"`
+	// Key is where the finished file was stored on the filesystem
+	Key string `json:"key,omitempty"`
+	// ContentType is the type found in the data of the finished upload
+	ContentType string `json:"content_type,omitempty"`
+}
+
+// Complete reports whether all the bytes of the upload were received
+func (u Upload) Complete() bool {
+	return u.Offset == u.Length
+}
+
+// Store keeps uploads and their data while they are in progress
+type Store interface {
+	// Create saves a new upload, which has no data yet
+	Create(upload Upload) error
+	// Get returns an upload, or ErrNotFound
+	Get(id string) (Upload, error)
+	// Update saves the information of an upload
+	Update(upload Upload) error
+	// WriteChunk appends the data read from r to the upload, whose data is offset bytes long,
+	// and returns how many bytes were written
+	WriteChunk(id string, offset int64, r io.Reader) (int64, error)
+	// Reader returns the data of an upload
+	Reader(id string) (io.ReadCloser, error)
+	// DeleteData removes the data of an upload, but keeps its information
+	DeleteData(id string) error
+	// Delete removes an upload and its data
+	Delete(id string) error
+	// List returns every upload
+	List() ([]Upload, error)
+}
+
+var validID = regexp.MustCompile(`^[a-f0-9]{32}$`)
+
+// FileStore keeps uploads in a folder, such as the application's tmp/tus: each upload has a
+// .json file with its information and a .bin file with its data
+type FileStore struct {
+	Dir string
+}
+
+func (f *FileStore) Create(upload Upload) error {
+	err := os.MkdirAll(f.Dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(f.path(upload.ID, ".bin"), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	_ = file.Close()
+
+	return f.Update(upload)
+}
+
+func (f *FileStore) Get(id string) (Upload, error) {
+	var upload Upload
+	if !validID.MatchString(id) {
+		return upload, ErrNotFound
+	}
+
+	content, err := os.ReadFile(f.path(id, ".json"))
+	if os.IsNotExist(err) {
+		return upload, ErrNotFound
+	}
+	if err != nil {
+		return upload, err
+	}
+
+	err = json.Unmarshal(content, &upload)
+	return upload, err
+}
+
+func (f *FileStore) Update(upload Upload) error {
+	content, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+
+	// write and rename, so the information is never left half written
+	tmp := f.path(upload.ID, ".json.tmp")
+	err = os.WriteFile(tmp, content, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.path(upload.ID, ".json"))
+}
+
+func (f *FileStore) WriteChunk(id string, offset int64, r io.Reader) (int64, error) {
+	file, err := os.OpenFile(f.path(id, ".bin"), os.O_WRONLY, 0644)
+	if os.IsNotExist(err) {
+		return 0, ErrNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	// a previous request may have written data after offset before failing; it is overwritten
+	_, err = file.Seek(offset, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(file, r)
+	if truncErr := file.Truncate(offset + n); err == nil {
+		err = truncErr
+	}
+	return n, err
+}
+
+func (f *FileStore) Reader(id string) (io.ReadCloser, error) {
+	file, err := os.Open(f.path(id, ".bin"))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return file, err
+}
+
+func (f *FileStore) DeleteData(id string) error {
+	err := os.Remove(f.path(id, ".bin"))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (f *FileStore) Delete(id string) error {
+	err := f.DeleteData(id)
+	if err != nil {
+		return err
+	}
+	err = os.Remove(f.path(id, ".json"))
+	if os.IsNotExist(err) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (f *FileStore) List() ([]Upload, error) {
+	entries, err := os.ReadDir(f.Dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var uploads []Upload
+	for _, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		upload, err := f.Get(strings.TrimSuffix(entry.Name(), ".json"))
+		if err != nil {
+			continue
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
+
+func (f *FileStore) path(id, ext string) string {
+	return filepath.Join(f.Dir, id+ext)
+}
@@ -0,0 +1,457 @@
+// Package tus implements a server for the tus resumable upload protocol, version 1.0.0, with the
+// creation, expiration and termination extensions. See https://tus.io/protocols/resumable-upload
+package tus
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+	"github.com/gabriel-vasile/mimetype"
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	Version    = "1.0.0"
+	Extensions = "creation,expiration,termination"
+)
+
+// sniffLen is how much of an upload is read to find its type
+const sniffLen = 3072
+
+// ErrRejected is wrapped by the errors of Handler.Validate that refuse an upload for good
+var ErrRejected = errors.New("tus: upload rejected")
+
+// Handler is a tus server. It is an http.Handler meant to be mounted on a chi router, e.g.
+//
+//	mux.Mount("/uploads", &tus.Handler{Store: &tus.FileStore{Dir: "tmp/tus"}, FS: fs})
+//
+// Partial uploads are kept in the store. Once all of an upload is received, it is stored on FS
+// under Destination and its data is removed from the store
+type Handler struct {
+	Store Store
+	// FS receives finished uploads; when nil, they are left in the store for OnComplete to handle
+	FS          filesystems.FS
+	Destination string
+	// MaxSize is the largest upload accepted, in bytes; 0 means no limit
+	MaxSize int64
+	// Expiration is how long an upload is kept after it was last written to; it defaults to 24 hours
+	Expiration time.Duration
+	// Validate reads the data of a finished upload before it is stored, e.g. to check its
+	// ContentType, the type found in its data, or scan it. Uploads it returns an error wrapping
+	// ErrRejected for are removed and answered with 422 Unprocessable Entity; other errors leave
+	// the upload to be finished again
+	Validate func(ctx context.Context, upload Upload, r io.Reader) error
+	// OnComplete is called once an upload is finished and stored on FS
+	OnComplete func(ctx context.Context, upload Upload)
+	ErrorLog   *log.Logger
+
+	once   sync.Once
+	router chi.Router
+	locks  sync.Map
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.once.Do(h.routes)
+
+	// X-HTTP-Method-Override is honoured for clients that can't send PATCH or DELETE. It has to be
+	// applied before routing, including in the router h is mounted on, which already routed a POST
+	if method := r.Header.Get("X-HTTP-Method-Override"); method != "" && r.Method == http.MethodPost {
+		r.Method = strings.ToUpper(method)
+		if rctx := chi.RouteContext(r.Context()); rctx != nil {
+			rctx.RouteMethod = r.Method
+		}
+	}
+
+	h.router.ServeHTTP(w, r)
+}
+
+func (h *Handler) routes() {
+	mux := chi.NewRouter()
+	mux.Use(h.protocol)
+	mux.Options("/", h.options)
+	mux.Post("/", h.create)
+	mux.Head("/{id}", h.head)
+	mux.Patch("/{id}", h.patch)
+	mux.Delete("/{id}", h.terminate)
+	h.router = mux
+}
+
+// protocol adds the Tus-Resumable header to every response, and checks the version asked for
+func (h *Handler) protocol(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Tus-Resumable", Version)
+
+		if r.Method != http.MethodOptions && r.Header.Get("Tus-Resumable") != Version {
+			w.Header().Set("Tus-Version", Version)
+			http.Error(w, "unsupported tus version", http.StatusPreconditionFailed)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (h *Handler) options(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Tus-Version", Version)
+	w.Header().Set("Tus-Extension", Extensions)
+	if h.MaxSize > 0 {
+		w.Header().Set("Tus-Max-Size", strconv.FormatInt(h.MaxSize, 10))
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) create(w http.ResponseWriter, r *http.Request) {
+	length, err := strconv.ParseInt(r.Header.Get("Upload-Length"), 10, 64)
+	if err != nil || length < 0 {
+		http.Error(w, "missing or invalid Upload-Length", http.StatusBadRequest)
+		return
+	}
+	if h.MaxSize > 0 && length > h.MaxSize {
+		http.Error(w, "upload is too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	metadata, err := ParseMetadata(r.Header.Get("Upload-Metadata"))
+	if err != nil {
+		http.Error(w, "invalid Upload-Metadata", http.StatusBadRequest)
+		return
+	}
+
+	id, err := newID()
+	if err != nil {
+		h.serverError(w, err)
+		return
+	}
+
+	upload := Upload{
+		ID:       id,
+		Length:   length,
+		Metadata: metadata,
+		Expires:  time.Now().Add(h.expiration()),
+	}
+	err = h.Store.Create(upload)
+	if err != nil {
+		h.serverError(w, err)
+		return
+	}
+
+	if upload.Complete() {
+		upload, err = h.finish(r.Context(), upload)
+		if err != nil {
+			// an empty upload can't be resumed, so the client has to create it again
+			_ = h.Store.Delete(id)
+			h.finishError(w, upload, err)
+			return
+		}
+	}
+
+	w.Header().Set("Location", strings.TrimSuffix(r.URL.Path, "/")+"/"+id)
+	w.Header().Set("Upload-Expires", upload.Expires.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (h *Handler) head(w http.ResponseWriter, r *http.Request) {
+	upload, ok := h.upload(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Length", strconv.FormatInt(upload.Length, 10))
+	w.Header().Set("Upload-Expires", upload.Expires.UTC().Format(http.TimeFormat))
+	if len(upload.Metadata) > 0 {
+		w.Header().Set("Upload-Metadata", FormatMetadata(upload.Metadata))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *Handler) patch(w http.ResponseWriter, r *http.Request) {
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType != "application/offset+octet-stream" {
+		http.Error(w, "Content-Type must be application/offset+octet-stream", http.StatusUnsupportedMediaType)
+		return
+	}
+
+	offset, err := strconv.ParseInt(r.Header.Get("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		http.Error(w, "missing or invalid Upload-Offset", http.StatusBadRequest)
+		return
+	}
+
+	unlock := h.lock(chi.URLParam(r, "id"))
+	defer unlock()
+
+	upload, ok := h.upload(w, r)
+	if !ok {
+		return
+	}
+	if offset != upload.Offset {
+		http.Error(w, "Upload-Offset does not match the offset of the upload", http.StatusConflict)
+		return
+	}
+	if upload.Complete() {
+		http.Error(w, "the upload is already complete", http.StatusForbidden)
+		return
+	}
+
+	// data beyond the length of the upload is ignored
+	left := upload.Length - upload.Offset
+	n, writeErr := h.Store.WriteChunk(upload.ID, upload.Offset, io.LimitReader(r.Body, left))
+
+	upload.Offset += n
+	upload.Expires = time.Now().Add(h.expiration())
+
+	if writeErr == nil && upload.Complete() {
+		// the final offset is only saved by finish, so when storing the upload fails the client
+		// still sees it incomplete, and sends the last chunk again to retry
+		upload, err = h.finish(r.Context(), upload)
+		if err != nil {
+			h.finishError(w, upload, err)
+			return
+		}
+	} else {
+		// whatever was received is kept, so the client can resume from there
+		err = h.Store.Update(upload)
+		if err != nil {
+			h.serverError(w, err)
+			return
+		}
+		if writeErr != nil {
+			h.serverError(w, writeErr)
+			return
+		}
+	}
+
+	w.Header().Set("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	w.Header().Set("Upload-Expires", upload.Expires.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) terminate(w http.ResponseWriter, r *http.Request) {
+	unlock := h.lock(chi.URLParam(r, "id"))
+	defer unlock()
+
+	upload, ok := h.upload(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.Store.Delete(upload.ID)
+	if err != nil {
+		h.serverError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Cleanup removes the uploads that expired. Schedule it to run regularly
+func (h *Handler) Cleanup() error {
+	uploads, err := h.Store.List()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, upload := range uploads {
+		if upload.Expires.Before(now) {
+			err = h.Store.Delete(upload.ID)
+			if err != nil && !errors.Is(err, ErrNotFound) {
+				return err
+			}
+			h.locks.Delete(upload.ID)
+		}
+	}
+	return nil
+}
+
+// upload returns the upload named in the url, answering with 404 Not Found when there is no
+// such upload or it expired
+func (h *Handler) upload(w http.ResponseWriter, r *http.Request) (Upload, bool) {
+	upload, err := h.Store.Get(chi.URLParam(r, "id"))
+	if errors.Is(err, ErrNotFound) {
+		http.NotFound(w, r)
+		return upload, false
+	}
+	if err != nil {
+		h.serverError(w, err)
+		return upload, false
+	}
+
+	if upload.Expires.Before(time.Now()) {
+		_ = h.Store.Delete(upload.ID)
+		http.NotFound(w, r)
+		return upload, false
+	}
+
+	return upload, true
+}
+
+// finish validates a finished upload and stores it on FS, named with its id and the extension of
+// the type found in its data, saves it as complete, and frees its data from the store. The
+// filename and filetype sent in its metadata are left for OnComplete, since clients can send any
+func (h *Handler) finish(ctx context.Context, upload Upload) (Upload, error) {
+	src, err := h.Store.Reader(upload.ID)
+	if err != nil {
+		return upload, err
+	}
+	mimeType, data, err := detectType(src)
+	if err == nil {
+		upload.ContentType = mimeType.String()
+		if h.Validate != nil {
+			err = h.Validate(ctx, upload, data)
+		}
+	}
+	_ = src.Close()
+	if err != nil {
+		return upload, err
+	}
+
+	if h.FS == nil {
+		err := h.Store.Update(upload)
+		if err != nil {
+			return upload, err
+		}
+		h.complete(ctx, upload)
+		return upload, nil
+	}
+
+	upload.Key = path.Join(h.Destination, upload.ID+mimeType.Extension())
+
+	src, err = h.Store.Reader(upload.ID)
+	if err != nil {
+		return upload, err
+	}
+	defer src.Close()
+
+	err = h.FS.PutStream(ctx, upload.Key, src, filesystems.PutOptions{
+		ContentType: upload.ContentType,
+		Size:        upload.Length,
+	})
+	if err != nil {
+		return upload, err
+	}
+
+	err = h.Store.Update(upload)
+	if err != nil {
+		return upload, err
+	}
+	// the upload is stored, so failing to free its data only leaves it for Cleanup
+	err = h.Store.DeleteData(upload.ID)
+	if err != nil && h.ErrorLog != nil {
+		h.ErrorLog.Println(err)
+	}
+
+	h.complete(ctx, upload)
+	return upload, nil
+}
+
+// finishError answers a request whose upload could not be finished, removing the upload when
+// Validate rejected it
+func (h *Handler) finishError(w http.ResponseWriter, upload Upload, err error) {
+	if !errors.Is(err, ErrRejected) {
+		h.serverError(w, err)
+		return
+	}
+
+	if err := h.Store.Delete(upload.ID); err != nil && !errors.Is(err, ErrNotFound) && h.ErrorLog != nil {
+		h.ErrorLog.Println(err)
+	}
+	h.locks.Delete(upload.ID)
+	http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+}
+
+// detectType finds the type of the data read from src by its first bytes, and returns a reader
+// that still reads all of it
+func detectType(src io.Reader) (*mimetype.MIME, io.Reader, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, nil, err
+	}
+	head = head[:n]
+
+	return mimetype.Detect(head), io.MultiReader(bytes.NewReader(head), src), nil
+}
+
+func (h *Handler) complete(ctx context.Context, upload Upload) {
+	if h.OnComplete != nil {
+		h.OnComplete(ctx, upload)
+	}
+}
+
+// lock serializes the requests writing to an upload
+func (h *Handler) lock(id string) func() {
+	value, _ := h.locks.LoadOrStore(id, &sync.Mutex{})
+	mu := value.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+func (h *Handler) expiration() time.Duration {
+	if h.Expiration <= 0 {
+		return 24 * time.Hour
+	}
+	return h.Expiration
+}
+
+func (h *Handler) serverError(w http.ResponseWriter, err error) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Println(err)
+	}
+	http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+}
+
+// ParseMetadata decodes an Upload-Metadata header: comma separated pairs of a key and its base64
+// encoded value
+func ParseMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+
+		key, encoded, _ := strings.Cut(pair, " ")
+		value, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil {
+			return nil, fmt.Errorf("tus: invalid metadata value for %s: %w", key, err)
+		}
+		metadata[key] = string(value)
+	}
+	return metadata, nil
+}
+
+// FormatMetadata encodes metadata for an Upload-Metadata header
+func FormatMetadata(metadata map[string]string) string {
+	pairs := make([]string, 0, len(metadata))
+	for key, value := range metadata {
+		pairs = append(pairs, key+" "+base64.StdEncoding.EncodeToString([]byte(value)))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func newID() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,336 @@
+package tus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+	"github.com/FernandoJVideira/velox/filesystems/memfilesystem"
+	"github.com/go-chi/chi/v5"
+)
+
+func testStores() map[string]Store {
+	return map[string]Store{
+		"file":   &FileStore{Dir: path.Join(testDir, "files")},
+		"badger": &BadgerStore{Conn: testBadger, Prefix: "test:"},
+	}
+}
+
+func request(t *testing.T, h http.Handler, method, target string, headers map[string]string, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(method, target, strings.NewReader(body))
+	r.Header.Set("Tus-Resumable", Version)
+	for k, v := range headers {
+		r.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+func TestHandler_Upload(t *testing.T) {
+	for name, store := range testStores() {
+		t.Run(name, func(t *testing.T) {
+			fs := &memfilesystem.Mem{}
+			var completed Upload
+			h := &Handler{
+				Store:       store,
+				FS:          fs,
+				Destination: "videos",
+				OnComplete:  func(ctx context.Context, upload Upload) { completed = upload },
+			}
+
+			w := request(t, h, "POST", "/", map[string]string{
+				"Upload-Length":   "11",
+				"Upload-Metadata": FormatMetadata(map[string]string{"filename": "clip.html", "filetype": "text/html"}),
+			}, "")
+			if w.Code != http.StatusCreated {
+				t.Fatalf("expected 201 creating the upload, got %d: %s", w.Code, w.Body.String())
+			}
+			location := w.Header().Get("Location")
+			if w.Header().Get("Tus-Resumable") != Version || w.Header().Get("Upload-Expires") == "" {
+				t.Error("missing protocol headers on creation")
+			}
+
+			w = request(t, h, "PATCH", location, map[string]string{
+				"Content-Type":  "application/offset+octet-stream",
+				"Upload-Offset": "0",
+			}, "hello ")
+			if w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "6" {
+				t.Fatalf("expected offset 6 after the first chunk, got %d %s", w.Code, w.Header().Get("Upload-Offset"))
+			}
+
+			w = request(t, h, "HEAD", location, nil, "")
+			if w.Code != http.StatusOK || w.Header().Get("Upload-Offset") != "6" || w.Header().Get("Upload-Length") != "11" {
+				t.Errorf("wrong HEAD response: %d %v", w.Code, w.Header())
+			}
+
+			w = request(t, h, "PATCH", location, map[string]string{
+				"Content-Type":  "application/offset+octet-stream",
+				"Upload-Offset": "0",
+			}, "again")
+			if w.Code != http.StatusConflict {
+				t.Errorf("expected 409 for a wrong offset, got %d", w.Code)
+			}
+
+			w = request(t, h, "PATCH", location, map[string]string{
+				"Content-Type":  "application/offset+octet-stream",
+				"Upload-Offset": "6",
+			}, "world")
+			if w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "11" {
+				t.Fatalf("expected offset 11 after the last chunk, got %d %s", w.Code, w.Header().Get("Upload-Offset"))
+			}
+
+			// the name and type sent by the client are ignored for those found in the data
+			key := "videos/" + path.Base(location) + ".txt"
+			if got := string(fs.Content(key)); got != "hello world" {
+				t.Errorf("expected the finished upload at %s, got %q (%v)", key, got, fs.Keys())
+			}
+			if file, _ := fs.File(key); file.ContentType != "text/plain; charset=utf-8" {
+				t.Errorf("expected the upload to be stored as text, got %q", file.ContentType)
+			}
+			if completed.Key != key || completed.ContentType != "text/plain; charset=utf-8" || completed.Metadata["filename"] != "clip.html" {
+				t.Errorf("OnComplete was not called with the stored upload: %+v", completed)
+			}
+		})
+	}
+}
+
+func TestHandler_Protocol(t *testing.T) {
+	h := &Handler{Store: &FileStore{Dir: path.Join(testDir, "protocol")}, MaxSize: 10}
+
+	var tests = []struct {
+		name     string
+		method   string
+		target   string
+		headers  map[string]string
+		expected int
+	}{
+		{"options", "OPTIONS", "/", nil, http.StatusNoContent},
+		{"missing length", "POST", "/", nil, http.StatusBadRequest},
+		{"too large", "POST", "/", map[string]string{"Upload-Length": "11"}, http.StatusRequestEntityTooLarge},
+		{"bad metadata", "POST", "/", map[string]string{"Upload-Length": "1", "Upload-Metadata": "name %%%"}, http.StatusBadRequest},
+		{"unknown upload", "HEAD", "/0123456789abcdef0123456789abcdef", nil, http.StatusNotFound},
+		{"invalid id", "HEAD", "/..%2f..%2fetc", nil, http.StatusNotFound},
+		{"wrong content type", "PATCH", "/0123456789abcdef0123456789abcdef", map[string]string{"Upload-Offset": "0"}, http.StatusUnsupportedMediaType},
+		{"wrong version", "POST", "/", map[string]string{"Tus-Resumable": "0.2.2", "Upload-Length": "1"}, http.StatusPreconditionFailed},
+	}
+
+	for _, e := range tests {
+		w := request(t, h, e.method, e.target, e.headers, "")
+		if w.Code != e.expected {
+			t.Errorf("%s: expected %d, got %d", e.name, e.expected, w.Code)
+		}
+	}
+
+	w := request(t, h, "OPTIONS", "/", nil, "")
+	if w.Header().Get("Tus-Extension") != Extensions || w.Header().Get("Tus-Max-Size") != "10" {
+		t.Error("wrong OPTIONS headers:", w.Header())
+	}
+}
+
+func TestHandler_Termination(t *testing.T) {
+	for name, store := range testStores() {
+		t.Run(name, func(t *testing.T) {
+			h := &Handler{Store: store}
+
+			w := request(t, h, "POST", "/", map[string]string{"Upload-Length": "5"}, "")
+			location := w.Header().Get("Location")
+
+			w = request(t, h, "POST", location, map[string]string{"X-HTTP-Method-Override": "DELETE"}, "")
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("expected 204 terminating the upload, got %d", w.Code)
+			}
+
+			w = request(t, h, "HEAD", location, nil, "")
+			if w.Code != http.StatusNotFound {
+				t.Errorf("expected 404 after termination, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func TestHandler_MountedMethodOverride(t *testing.T) {
+	h := &Handler{Store: &FileStore{Dir: path.Join(testDir, "mounted")}}
+	mux := chi.NewRouter()
+	mux.Mount("/uploads", h)
+
+	w := request(t, mux, "POST", "/uploads/", map[string]string{"Upload-Length": "5"}, "")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("expected 201 creating the upload, got %d", w.Code)
+	}
+	location := w.Header().Get("Location")
+	if !strings.HasPrefix(location, "/uploads/") {
+		t.Errorf("expected the location under the mount point, got %s", location)
+	}
+
+	w = request(t, mux, "POST", location, map[string]string{
+		"X-HTTP-Method-Override": "PATCH",
+		"Content-Type":           "application/offset+octet-stream",
+		"Upload-Offset":          "0",
+	}, "hel")
+	if w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "3" {
+		t.Fatalf("expected the overridden PATCH to write, got %d %s", w.Code, w.Body.String())
+	}
+
+	w = request(t, mux, "POST", location, map[string]string{"X-HTTP-Method-Override": "delete"}, "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("expected the overridden DELETE to terminate the upload, got %d", w.Code)
+	}
+	w = request(t, mux, "HEAD", location, nil, "")
+	if w.Code != http.StatusNotFound {
+		t.Errorf("expected 404 after termination, got %d", w.Code)
+	}
+}
+
+// failingFS fails to store files while fail is set
+type failingFS struct {
+	*memfilesystem.Mem
+	fail bool
+}
+
+func (f *failingFS) PutStream(ctx context.Context, key string, r io.Reader, opts filesystems.PutOptions) error {
+	if f.fail {
+		_, _ = io.Copy(io.Discard, r)
+		return errors.New("storage is down")
+	}
+	return f.Mem.PutStream(ctx, key, r, opts)
+}
+
+func TestHandler_FinishRetry(t *testing.T) {
+	for name, store := range testStores() {
+		t.Run(name, func(t *testing.T) {
+			fs := &failingFS{Mem: &memfilesystem.Mem{}, fail: true}
+			h := &Handler{Store: store, FS: fs}
+
+			w := request(t, h, "POST", "/", map[string]string{"Upload-Length": "11"}, "")
+			location := w.Header().Get("Location")
+			patch := func(offset, body string) *httptest.ResponseRecorder {
+				return request(t, h, "PATCH", location, map[string]string{
+					"Content-Type":  "application/offset+octet-stream",
+					"Upload-Offset": offset,
+				}, body)
+			}
+
+			patch("0", "hello ")
+			w = patch("6", "world")
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("expected 500 when the upload can't be stored, got %d", w.Code)
+			}
+
+			w = request(t, h, "HEAD", location, nil, "")
+			if w.Header().Get("Upload-Offset") != "6" {
+				t.Fatalf("expected the upload to still be incomplete, got offset %s", w.Header().Get("Upload-Offset"))
+			}
+
+			fs.fail = false
+			w = patch("6", "world")
+			if w.Code != http.StatusNoContent || w.Header().Get("Upload-Offset") != "11" {
+				t.Fatalf("expected the retry to finish the upload, got %d %s", w.Code, w.Body.String())
+			}
+			if got := string(fs.Content(path.Base(location) + ".txt")); got != "hello world" {
+				t.Errorf("expected the finished upload to be stored, got %q (%v)", got, fs.Keys())
+			}
+		})
+	}
+}
+
+func TestHandler_Validate(t *testing.T) {
+	for name, store := range testStores() {
+		t.Run(name, func(t *testing.T) {
+			fs := &memfilesystem.Mem{}
+			var validateErr error
+			var validated string
+			h := &Handler{
+				Store: store,
+				FS:    fs,
+				Validate: func(ctx context.Context, upload Upload, r io.Reader) error {
+					data, _ := io.ReadAll(r)
+					validated = string(data)
+					return validateErr
+				},
+			}
+
+			upload := func(body string) (string, *httptest.ResponseRecorder) {
+				w := request(t, h, "POST", "/", map[string]string{"Upload-Length": strconv.Itoa(len(body))}, "")
+				location := w.Header().Get("Location")
+				return location, request(t, h, "PATCH", location, map[string]string{
+					"Content-Type":  "application/offset+octet-stream",
+					"Upload-Offset": "0",
+				}, body)
+			}
+
+			validateErr = fmt.Errorf("%w: not a video", ErrRejected)
+			location, w := upload("rejected")
+			if w.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("expected 422 for a rejected upload, got %d", w.Code)
+			}
+			if validated != "rejected" {
+				t.Errorf("expected Validate to read the upload, got %q", validated)
+			}
+			if w = request(t, h, "HEAD", location, nil, ""); w.Code != http.StatusNotFound {
+				t.Errorf("expected the rejected upload to be removed, got %d", w.Code)
+			}
+
+			validateErr = errors.New("scanner is down")
+			location, w = upload("retried")
+			if w.Code != http.StatusInternalServerError {
+				t.Fatalf("expected 500 when the upload can't be validated, got %d", w.Code)
+			}
+			validateErr = nil
+			w = request(t, h, "PATCH", location, map[string]string{
+				"Content-Type":  "application/offset+octet-stream",
+				"Upload-Offset": "0",
+			}, "retried")
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("expected the retry to finish the upload, got %d", w.Code)
+			}
+
+			if keys := fs.Keys(); len(keys) != 1 || string(fs.Content(keys[0])) != "retried" {
+				t.Errorf("expected only the valid upload to be stored, got %v", keys)
+			}
+		})
+	}
+}
+
+func TestHandler_Expiration(t *testing.T) {
+	for name, store := range testStores() {
+		t.Run(name, func(t *testing.T) {
+			h := &Handler{Store: store, Expiration: time.Millisecond}
+
+			w := request(t, h, "POST", "/", map[string]string{"Upload-Length": "5"}, "")
+			id := path.Base(w.Header().Get("Location"))
+			time.Sleep(5 * time.Millisecond)
+
+			err := h.Cleanup()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := store.Get(id); err != ErrNotFound {
+				t.Error("expected the expired upload to be removed, got", err)
+			}
+		})
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	metadata, err := ParseMetadata("filename d29ybGRfZG9taW5hdGlvbl9wbGFuLnBkZg==,is_confidential")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["filename"] != "world_domination_plan.pdf" {
+		t.Error("wrong filename:", metadata["filename"])
+	}
+	if _, ok := metadata["is_confidential"]; !ok {
+		t.Error("expected keys without values to be kept")
+	}
+}
@@ -0,0 +1,76 @@
+package velox
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/FernandoJVideira/velox/filesystems/memfilesystem"
+	"github.com/FernandoJVideira/velox/tus"
+)
+
+// signatureScanner finds a threat in files that contain its signature
+type signatureScanner struct {
+	signature string
+}
+
+func (s signatureScanner) Scan(ctx context.Context, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	if strings.Contains(string(data), s.signature) {
+		return s.signature, nil
+	}
+	return "", nil
+}
+
+func TestNewTusHandler_Validate(t *testing.T) {
+	v := newTestVelox()
+	v.RootPath = t.TempDir()
+	v.config.uploads.maxUploadSize = 1 << 20
+	v.config.uploads.allowedMimeTypes = []string{"text/plain"}
+	v.UploadScanner = signatureScanner{"EICAR"}
+
+	fs := &memfilesystem.Mem{}
+	h := v.NewTusHandler(fs, "uploads")
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+	}{
+		{"allowed", "some notes", http.StatusNoContent},
+		{"wrong type", "%PDF-1.4 not notes", http.StatusUnprocessableEntity},
+		{"infected", "notes with EICAR in them", http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/", nil)
+			r.Header.Set("Tus-Resumable", tus.Version)
+			r.Header.Set("Upload-Length", strconv.Itoa(len(tt.body)))
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			r = httptest.NewRequest("PATCH", w.Header().Get("Location"), strings.NewReader(tt.body))
+			r.Header.Set("Tus-Resumable", tus.Version)
+			r.Header.Set("Content-Type", "application/offset+octet-stream")
+			r.Header.Set("Upload-Offset", "0")
+			w = httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != tt.status {
+				t.Errorf("expected %d, got %d: %s", tt.status, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	if keys := fs.Keys(); len(keys) != 1 || string(fs.Content(keys[0])) != "some notes" {
+		t.Errorf("expected only the allowed upload to be stored, got %v", keys)
+	}
+}
@@ -10,11 +10,17 @@ type initPaths struct {
 
 // config is a struct that holds the cookie configuration
 type cookieConfig struct {
-	name     string
-	lifetime string
-	persist  string
-	secure   string
-	domain   string
+	name         string
+	lifetime     string
+	idleTimeout  string
+	persist      string
+	secure       string
+	domain       string
+	path         string
+	sameSite     string
+	csrfSameSite string
+	httpOnly     string
+	partitioned  string
 }
 
 type dbConfig struct {
@@ -0,0 +1,84 @@
+package velox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// UploadScanner checks uploads for malware before they are stored, such as clamav.Client, which
+// is used when CLAMD_ADDRESS is set
+type UploadScanner interface {
+	// Scan reads a file from r and returns the name of the threat found in it, or an empty string
+	// when the file is clean
+	Scan(ctx context.Context, r io.Reader) (string, error)
+}
+
+// UploadInfectedError is returned for uploads a scanner found a threat in; it wraps ErrUploadInfected
+type UploadInfectedError struct {
+	Signature string
+}
+
+func (e *UploadInfectedError) Error() string {
+	return fmt.Sprintf("%s: %s", ErrUploadInfected, e.Signature)
+}
+
+func (e *UploadInfectedError) Unwrap() error {
+	return ErrUploadInfected
+}
+
+// scanUpload writes src to a temporary file and scans it. Uploads are refused when they can't be
+// scanned. The file is returned at its start, to be stored, and removed when closed
+func (v *Velox) scanUpload(ctx context.Context, src io.Reader, scanner UploadScanner) (io.ReadCloser, error) {
+	dir := filepath.Join(v.RootPath, "tmp")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.CreateTemp(dir, "scan-*")
+	if err != nil {
+		return nil, err
+	}
+	spooled := &tempFile{file}
+
+	_, err = io.Copy(file, src)
+	if err == nil {
+		_, err = file.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = spooled.Close()
+		return nil, err
+	}
+
+	signature, err := scanner.Scan(ctx, file)
+	if err != nil {
+		_ = spooled.Close()
+		return nil, fmt.Errorf("%w: %w", ErrUploadScan, err)
+	}
+	if signature != "" {
+		_ = spooled.Close()
+		return nil, &UploadInfectedError{Signature: signature}
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		_ = spooled.Close()
+		return nil, err
+	}
+
+	return spooled, nil
+}
+
+// tempFile is a file that is removed once closed
+type tempFile struct {
+	*os.File
+}
+
+func (t *tempFile) Close() error {
+	err := t.File.Close()
+	_ = os.Remove(t.Name())
+	return err
+}
@@ -1,81 +1,464 @@
 package velox
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"mime/multipart"
 	"net/http"
-	"os"
 	"path"
+	"path/filepath"
+	"strings"
 
 	"github.com/FernandoJVideira/velox/filesystems"
+	"github.com/FernandoJVideira/velox/filesystems/localfilesystem"
+	"github.com/FernandoJVideira/velox/images"
 	"github.com/gabriel-vasile/mimetype"
 )
 
-func (v *Velox) UploadFile(r *http.Request, destination, field string, fs filesystems.FS) error {
-	fileName, err := v.getFileToUpload(r, field)
+// sniffLen is how much of a file is read to detect its type
+const sniffLen = 3072
+
+// defaultMaxUploadSize is the largest file accepted when MAX_UPLOAD_SIZE is not set
+const defaultMaxUploadSize = 10 << 20
+
+var (
+	ErrUploadTooLarge      = errors.New("upload: file is too large")
+	ErrUploadType          = errors.New("upload: invalid file type")
+	ErrUploadExtension     = errors.New("upload: invalid file extension")
+	ErrUploadTooMany       = errors.New("upload: too many files")
+	ErrUploadTotalTooLarge = errors.New("upload: files are too large in total")
+	ErrUploadInfected      = errors.New("upload: file is infected")
+	ErrUploadScan          = errors.New("upload: file could not be scanned")
+)
+
+// UploadedFile describes a stored upload
+type UploadedFile struct {
+	// Key is where the file was stored: its key on the filesystem, or its path on local disk
+	Key          string
+	OriginalName string
+	Size         int64
+	MimeType     string
+	// Checksum is the hex encoded SHA-256 of the file
+	Checksum string
+	// Variants are the keys of the resized copies of an image, by variant name
+	Variants map[string]string
+}
+
+// UploadFile stores the file sent in field under destination, either on fs or, when fs is nil,
+// on local disk. The file is streamed from the request as it is read, given a random name with
+// an extension matching its type, and checked against ALLOWED_FILETYPES and MAX_UPLOAD_SIZE.
+// When v.UploadScanner is set, files are scanned before they are stored, and when v.Images is set,
+// the variants of images are stored next to them. Files stored on fs are private, and those stored
+// on local disk are public, readable by the web server; UploadFiles lets the visibility be chosen
+func (v *Velox) UploadFile(r *http.Request, destination, field string, fs filesystems.FS) (*UploadedFile, error) {
+	file, fileName, err := v.openUpload(r, field)
 	if err != nil {
 		v.ErrorLog.Println(err)
-		return err
+		return nil, err
 	}
+	defer file.Close()
 
-	if fs != nil {
-		err = fs.Put(fileName, destination)
+	uploaded, err := v.storeUpload(r.Context(), file, fileName, UploadOptions{
+		Destination:      destination,
+		FS:               fs,
+		AllowedMimeTypes: v.config.uploads.allowedMimeTypes,
+		MaxFileSize:      v.config.uploads.maxUploadSize,
+		Images:           v.Images,
+		Scanner:          v.UploadScanner,
+	})
+	if err != nil {
+		v.ErrorLog.Println(err)
+		return nil, err
+	}
+
+	return uploaded, nil
+}
+
+// UploadOptions describes where UploadFiles stores files and which files it accepts
+type UploadOptions struct {
+	// Destination is the folder the files are stored in
+	Destination string
+	// FS is the filesystem the files are stored on; when it is nil, files are stored on local disk,
+	// in the Destination folder
+	FS filesystems.FS
+	// Visibility says who can read the stored files. It defaults to filesystems.Private, or to
+	// filesystems.Public on local disk, where files are often served from the public folder
+	Visibility filesystems.Visibility
+	// AllowedMimeTypes defaults to ALLOWED_FILETYPES
+	AllowedMimeTypes []string
+	// AllowedExtensions restricts the extensions of the names files are sent with, e.g. .jpg
+	AllowedExtensions []string
+	// MaxFileSize is the largest file accepted, in bytes; it defaults to MAX_UPLOAD_SIZE
+	MaxFileSize int64
+	// MaxTotalSize is the largest size of all the files together, in bytes; 0 means no limit
+	MaxTotalSize int64
+	// MaxFiles is how many files are accepted; 0 means no limit. Rejected files don't count towards
+	// it, so a file that is rejected doesn't keep a valid one sent after it from being stored
+	MaxFiles int
+	// Images makes the variants of images, which are stored next to them; it defaults to v.Images
+	Images *images.Processor
+	// Scanner checks files for malware before they are stored; it defaults to v.UploadScanner
+	Scanner UploadScanner
+}
+
+// UploadResult is the outcome of storing one of the files sent to UploadFiles
+type UploadResult struct {
+	OriginalName string
+	// File describes the stored file, and is nil when the file was rejected
+	File *UploadedFile
+	Err  error
+}
+
+// UploadFiles stores every file sent in field, as sent by an input with the multiple attribute
+// (the field may also be named field[]). Each file is checked and stored on its own, so the
+// results say which files were stored and why others were rejected; the error is only set when
+// the request can't be read
+func (v *Velox) UploadFiles(r *http.Request, field string, opts UploadOptions) ([]UploadResult, error) {
+	if opts.AllowedMimeTypes == nil {
+		opts.AllowedMimeTypes = v.config.uploads.allowedMimeTypes
+	}
+	if opts.MaxFileSize <= 0 {
+		opts.MaxFileSize = v.config.uploads.maxUploadSize
+	}
+	if opts.Images == nil {
+		opts.Images = v.Images
+	}
+	if opts.Scanner == nil {
+		opts.Scanner = v.UploadScanner
+	}
+
+	var results []UploadResult
+	var stored int
+	var total int64
+
+	store := func(src io.Reader, fileName string) {
+		result := UploadResult{OriginalName: filepath.Base(fileName)}
+		defer func() {
+			if result.Err != nil {
+				v.ErrorLog.Println(result.Err)
+			}
+			results = append(results, result)
+		}()
+
+		if opts.MaxFiles > 0 && stored >= opts.MaxFiles {
+			result.Err = fmt.Errorf("%w: at most %d are allowed", ErrUploadTooMany, opts.MaxFiles)
+			return
+		}
+		if !allowedExtension(fileName, opts.AllowedExtensions) {
+			result.Err = fmt.Errorf("%w: %s", ErrUploadExtension, filepath.Ext(fileName))
+			return
+		}
+
+		maxSize := opts.MaxFileSize
+		if opts.MaxTotalSize > 0 {
+			left := opts.MaxTotalSize - total
+			if left <= 0 {
+				result.Err = fmt.Errorf("%w: the limit is %d bytes", ErrUploadTotalTooLarge, opts.MaxTotalSize)
+				return
+			}
+			if maxSize <= 0 || left < maxSize {
+				maxSize = left
+			}
+		}
+
+		fileOpts := opts
+		fileOpts.MaxFileSize = maxSize
+		result.File, result.Err = v.storeUpload(r.Context(), src, fileName, fileOpts)
+		if errors.Is(result.Err, ErrUploadTooLarge) && maxSize < opts.MaxFileSize {
+			result.Err = fmt.Errorf("%w: the limit is %d bytes", ErrUploadTotalTooLarge, opts.MaxTotalSize)
+		}
+		if result.File != nil {
+			stored++
+			total += result.File.Size
+		}
+	}
+
+	if r.MultipartForm != nil {
+		headers := append(append([]*multipart.FileHeader{}, r.MultipartForm.File[field]...), r.MultipartForm.File[field+"[]"]...)
+		for _, header := range headers {
+			file, err := header.Open()
+			if err != nil {
+				results = append(results, UploadResult{OriginalName: filepath.Base(header.Filename), Err: err})
+				continue
+			}
+			store(file, header.Filename)
+			_ = file.Close()
+		}
+		return results, nil
+	}
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	if r.Form == nil {
+		_ = r.ParseForm()
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return results, nil
+		}
 		if err != nil {
-			v.ErrorLog.Println(err)
-			return err
+			return results, err
+		}
+
+		switch {
+		case part.FileName() == "":
+			value, err := io.ReadAll(io.LimitReader(part, 1<<20))
+			if err == nil {
+				r.Form.Add(part.FormName(), string(value))
+			}
+		case part.FormName() == field || part.FormName() == field+"[]":
+			store(part, part.FileName())
 		}
-	} else {
-		err = os.Rename(fileName, fmt.Sprintf("%s/%s", destination, path.Base(fileName)))
+	}
+}
+
+// openUpload returns the file sent in field. Unless the multipart form was already parsed, the
+// file is read straight from the request body, so it is never buffered to a temporary file; form
+// values sent before it are added to r.Form. NoSurf leaves the form unparsed when the CSRF token
+// is sent in a header or as the first field
+func (v *Velox) openUpload(r *http.Request, field string) (io.ReadCloser, string, error) {
+	if r.MultipartForm != nil {
+		file, header, err := r.FormFile(field)
 		if err != nil {
-			v.ErrorLog.Println(err)
-			return err
+			return nil, "", err
 		}
+		return file, header.Filename, nil
 	}
-	defer func() {
-		_ = os.Remove(fileName)
-	}()
 
-	return nil
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if r.Form == nil {
+		_ = r.ParseForm()
+	}
+
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, "", http.ErrMissingFile
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		if part.FileName() == "" {
+			// keep small form values, so handlers can still read them
+			value, err := io.ReadAll(io.LimitReader(part, 1<<20))
+			if err == nil {
+				r.Form.Add(part.FormName(), string(value))
+			}
+			continue
+		}
+
+		if part.FormName() == field {
+			return part, part.FileName(), nil
+		}
+	}
 }
 
-func (v *Velox) getFileToUpload(r *http.Request, fieldName string) (string, error) {
-	_ = r.ParseMultipartForm(v.config.uploads.maxUploadSize)
+// storeUpload checks the type and size of the file read from src, scans it, and stores it under
+// opts.Destination, along with its variants when it is an image opts.Images supports
+func (v *Velox) storeUpload(ctx context.Context, src io.Reader, fileName string, opts UploadOptions) (*UploadedFile, error) {
+	mimeType, src, err := detectType(src)
+	if err != nil {
+		return nil, err
+	}
+	if !allowedMimeType(mimeType, opts.AllowedMimeTypes) {
+		return nil, fmt.Errorf("%w: %s", ErrUploadType, mimeType.String())
+	}
 
-	file, header, err := r.FormFile(fieldName)
+	name, err := randomFileName(mimeType.Extension())
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	uploaded := &UploadedFile{
+		OriginalName: filepath.Base(fileName),
+		MimeType:     mimeType.String(),
+	}
+
+	counter := &uploadReader{
+		r:    src,
+		hash: sha256.New(),
+		max:  opts.MaxFileSize,
+	}
+
+	var body io.Reader = counter
+	if opts.Scanner != nil {
+		scanned, err := v.scanUpload(ctx, counter, opts.Scanner)
+		if err != nil {
+			return nil, err
+		}
+		defer scanned.Close()
+		body = scanned
+	}
+
+	visibility := opts.Visibility
+	if visibility == "" && opts.FS == nil {
+		visibility = filesystems.Public
+	}
+
+	store := func(name string, src io.Reader, contentType string) (string, error) {
+		putOpts := filesystems.PutOptions{
+			Visibility:  visibility,
+			ContentType: contentType,
+			Size:        -1,
+		}
+		if opts.FS == nil {
+			local := &localfilesystem.Local{Root: opts.Destination}
+			return filepath.Join(opts.Destination, name), local.PutStream(ctx, name, src, putOpts)
+		}
+		key := path.Join(opts.Destination, name)
+		return key, opts.FS.PutStream(ctx, key, src, putOpts)
+	}
+
+	if opts.Images != nil && opts.Images.Supports(mimeType.String()) {
+		err = storeImage(uploaded, body, name, opts.MaxFileSize, opts.Images, store)
+		if err != nil {
+			return nil, err
+		}
+		return uploaded, nil
 	}
-	defer file.Close()
 
-	mimeType, err := mimetype.DetectReader(file)
+	uploaded.Key, err = store(name, body, mimeType.String())
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+
+	uploaded.Size = counter.size
+	uploaded.Checksum = hex.EncodeToString(counter.hash.Sum(nil))
+	return uploaded, nil
+}
+
+// detectType returns the type of the file read from src, and a reader for all of the file
+func detectType(src io.Reader) (*mimetype.MIME, io.Reader, error) {
+	head := make([]byte, sniffLen)
+	n, err := io.ReadFull(src, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return nil, nil, err
 	}
+	head = head[:n]
+
+	return mimetype.Detect(head), io.MultiReader(bytes.NewReader(head), src), nil
+}
 
-	//go back to the beginning of the file
-	_, err = file.Seek(0, 0)
+// storeImage stores an image and its variants, which are named after it, e.g. name_thumb.jpg.
+// The image has to be read whole to be decoded, so it is kept in memory, and never read past
+// maxSize, or past the default MAX_UPLOAD_SIZE when there is no limit
+func storeImage(uploaded *UploadedFile, src io.Reader, name string, maxSize int64, processor *images.Processor, store func(string, io.Reader, string) (string, error)) error {
+	if maxSize <= 0 {
+		maxSize = defaultMaxUploadSize
+	}
+	data, err := io.ReadAll(io.LimitReader(src, maxSize+1))
 	if err != nil {
-		return "", err
+		return err
+	}
+	if int64(len(data)) > maxSize {
+		return fmt.Errorf("%w: the limit is %d bytes", ErrUploadTooLarge, maxSize)
 	}
 
-	if !inSlice(v.config.uploads.allowedMimeTypes, mimeType.String()) {
-		v.ErrorLog.Println(v.config.uploads.allowedMimeTypes)
-		return "", errors.New("invalid file type")
+	processed, err := processor.Process(data)
+	if errors.Is(err, images.ErrTooLarge) {
+		return fmt.Errorf("%w: %w", ErrUploadTooLarge, err)
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUploadType, err)
+	}
+
+	// a stripped original replaces the file as it was sent
+	if len(processed) > 0 && processed[0].Name == "" {
+		data = processed[0].Data
+		processed = processed[1:]
 	}
 
-	dst, err := os.Create(fmt.Sprintf("/tmp/%s", header.Filename))
+	uploaded.Key, err = store(name, bytes.NewReader(data), uploaded.MimeType)
 	if err != nil {
-		return "", err
+		return err
+	}
+	sum := sha256.Sum256(data)
+	uploaded.Size = int64(len(data))
+	uploaded.Checksum = hex.EncodeToString(sum[:])
+
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	uploaded.Variants = make(map[string]string, len(processed))
+	for _, img := range processed {
+		key, err := store(base+"_"+img.Name+img.Ext(), bytes.NewReader(img.Data), img.ContentType)
+		if err != nil {
+			return err
+		}
+		uploaded.Variants[img.Name] = key
+	}
+
+	return nil
+}
+
+// uploadReader counts and hashes what is read through it, and fails once more than max bytes are read
+type uploadReader struct {
+	r    io.Reader
+	hash hash.Hash
+	size int64
+	max  int64
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	u.size += int64(n)
+	u.hash.Write(p[:n])
+	if u.max > 0 && u.size > u.max {
+		return n, fmt.Errorf("%w: the limit is %d bytes", ErrUploadTooLarge, u.max)
 	}
-	defer dst.Close()
+	return n, err
+}
 
-	_, err = io.Copy(dst, file)
+// randomFileName returns a random file name with ext, which includes the dot
+func randomFileName(ext string) (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
 	if err != nil {
 		return "", err
 	}
+	return hex.EncodeToString(b) + ext, nil
+}
+
+// allowedExtension reports whether the extension of fileName is one of allowed, ignoring case;
+// any extension is allowed when allowed is empty
+func allowedExtension(fileName string, allowed []string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	ext := strings.ToLower(filepath.Ext(fileName))
+	for _, a := range allowed {
+		a = strings.ToLower(a)
+		if !strings.HasPrefix(a, ".") {
+			a = "." + a
+		}
+		if ext == a {
+			return true
+		}
+	}
+	return false
+}
 
-	return fmt.Sprintf("/tmp/%s", header.Filename), nil
+func allowedMimeType(mimeType *mimetype.MIME, allowed []string) bool {
+	for _, a := range allowed {
+		if a != "" && mimeType.Is(a) {
+			return true
+		}
+	}
+	return false
 }
 
 func inSlice(slice []string, value string) bool {
@@ -0,0 +1,408 @@
+package velox
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FernandoJVideira/velox/filesystems"
+	"github.com/FernandoJVideira/velox/filesystems/memfilesystem"
+	"github.com/FernandoJVideira/velox/images"
+)
+
+// testFile is a file sent in a multipart request
+type testFile struct {
+	field, name, content string
+}
+
+// filesRequest returns a request sending files, in order
+func filesRequest(files ...testFile) *http.Request {
+	body := new(bytes.Buffer)
+	mw := multipart.NewWriter(body)
+	for _, file := range files {
+		part, _ := mw.CreateFormFile(file.field, file.name)
+		_, _ = part.Write([]byte(file.content))
+	}
+	_ = mw.Close()
+
+	r := httptest.NewRequest("POST", "/", body)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	return r
+}
+
+// uploadRequest returns a request sending notes.txt in the file field
+func uploadRequest() *http.Request {
+	return filesRequest(testFile{"file", "notes.txt", "some notes"})
+}
+
+func TestUpload_Visibility(t *testing.T) {
+	v := newTestVelox()
+	v.RootPath = t.TempDir()
+	v.config.uploads.maxUploadSize = 1 << 20
+	v.config.uploads.allowedMimeTypes = []string{"text/plain"}
+
+	uploaded, err := v.UploadFile(uploadRequest(), t.TempDir(), "file", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, err := os.Stat(uploaded.Key); err != nil || info.Mode().Perm() != 0644 {
+		t.Errorf("expected a local upload to be public, got %v %v", info.Mode(), err)
+	}
+
+	results, err := v.UploadFiles(uploadRequest(), "file", UploadOptions{
+		Destination: t.TempDir(),
+		Visibility:  filesystems.Private,
+	})
+	if err != nil || len(results) != 1 || results[0].Err != nil {
+		t.Fatalf("expected the file to be stored, got %+v %v", results, err)
+	}
+	if info, err := os.Stat(results[0].File.Key); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("expected a private local upload, got %v %v", info.Mode(), err)
+	}
+
+	fs := &memfilesystem.Mem{}
+	uploaded, err = v.UploadFile(uploadRequest(), "notes", "file", fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if file, ok := fs.File(uploaded.Key); !ok || file.Visibility != filesystems.Private {
+		t.Errorf("expected an upload to a filesystem to be private, got %+v", file)
+	}
+}
+
+func TestUploadFiles(t *testing.T) {
+	v := newTestVelox()
+	v.config.uploads.maxUploadSize = 1 << 20
+	v.config.uploads.allowedMimeTypes = []string{"text/plain"}
+
+	notes := testFile{"files", "notes.txt", "some notes"}
+	pdf := testFile{"files", "report.pdf", "%PDF-1.4 a report"}
+
+	tests := []struct {
+		name     string
+		opts     UploadOptions
+		files    []testFile
+		expected []error
+	}{
+		{"every file", UploadOptions{},
+			[]testFile{notes, {"files[]", "more.txt", "more notes"}}, []error{nil, nil}},
+		{"per-file results", UploadOptions{},
+			[]testFile{notes, pdf, notes}, []error{nil, ErrUploadType, nil}},
+		{"max files", UploadOptions{MaxFiles: 2},
+			[]testFile{notes, notes, notes}, []error{nil, nil, ErrUploadTooMany}},
+		{"max files skips rejected", UploadOptions{MaxFiles: 2},
+			[]testFile{pdf, notes, notes, notes}, []error{ErrUploadType, nil, nil, ErrUploadTooMany}},
+		{"allowed extensions", UploadOptions{AllowedExtensions: []string{".txt"}},
+			[]testFile{notes, {"files", "notes.md", "some notes"}}, []error{nil, ErrUploadExtension}},
+		{"max file size", UploadOptions{MaxFileSize: 12},
+			[]testFile{notes, {"files", "long.txt", "some long notes"}}, []error{nil, ErrUploadTooLarge}},
+		{"max total size", UploadOptions{MaxTotalSize: 25},
+			[]testFile{notes, notes, notes}, []error{nil, nil, ErrUploadTotalTooLarge}},
+		{"max total size cut off", UploadOptions{MaxTotalSize: 15},
+			[]testFile{notes, notes}, []error{nil, ErrUploadTotalTooLarge}},
+		{"other fields", UploadOptions{},
+			[]testFile{{"avatar", "me.txt", "me"}, notes}, []error{nil}},
+	}
+
+	for _, parsed := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s parsed=%v", tt.name, parsed), func(t *testing.T) {
+				fs := &memfilesystem.Mem{}
+				opts := tt.opts
+				opts.FS = fs
+				opts.Destination = "docs"
+
+				r := filesRequest(tt.files...)
+				if parsed {
+					if err := r.ParseMultipartForm(1 << 20); err != nil {
+						t.Fatal(err)
+					}
+				}
+
+				results, err := v.UploadFiles(r, "files", opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(results) != len(tt.expected) {
+					t.Fatalf("expected %d results, got %+v", len(tt.expected), results)
+				}
+
+				var sent []testFile
+				for _, file := range tt.files {
+					if strings.TrimSuffix(file.field, "[]") == "files" {
+						sent = append(sent, file)
+					}
+				}
+				for i, result := range results {
+					if result.OriginalName != sent[i].name {
+						t.Errorf("%d: expected the result for %s, got %s", i, sent[i].name, result.OriginalName)
+					}
+					if !errors.Is(result.Err, tt.expected[i]) || (tt.expected[i] == nil) != (result.File != nil) {
+						t.Errorf("%d: expected %v, got %+v", i, tt.expected[i], result)
+						continue
+					}
+					if result.File == nil {
+						continue
+					}
+					if !strings.HasPrefix(result.File.Key, "docs/") || string(fs.Content(result.File.Key)) != sent[i].content {
+						t.Errorf("%d: expected %s to be stored, got %v", i, sent[i].name, fs.Keys())
+					}
+					if result.File.Size != int64(len(sent[i].content)) {
+						t.Errorf("%d: expected size %d, got %d", i, len(sent[i].content), result.File.Size)
+					}
+				}
+
+				stored := 0
+				for _, expected := range tt.expected {
+					if expected == nil {
+						stored++
+					}
+				}
+				if len(fs.Keys()) != stored {
+					t.Errorf("expected %d files to be stored, got %v", stored, fs.Keys())
+				}
+			})
+		}
+	}
+}
+
+// streamingFS closes received once it has read part of a file, while the rest is still being sent
+type streamingFS struct {
+	*memfilesystem.Mem
+	received chan struct{}
+}
+
+func (f *streamingFS) PutStream(ctx context.Context, key string, r io.Reader, opts filesystems.PutOptions) error {
+	head := make([]byte, 8<<10)
+	_, err := io.ReadFull(r, head)
+	close(f.received)
+	if err != nil {
+		return err
+	}
+	return f.Mem.PutStream(ctx, key, io.MultiReader(bytes.NewReader(head), r), opts)
+}
+
+func TestUpload_Streams(t *testing.T) {
+	v := newTestVelox()
+	v.config.uploads.maxUploadSize = 1 << 20
+	v.config.uploads.allowedMimeTypes = []string{"text/plain"}
+
+	fs := &streamingFS{Mem: &memfilesystem.Mem{}, received: make(chan struct{})}
+	content := strings.Repeat("some notes\n", 2000)
+
+	// the second half of the file is only sent once the filesystem received the first one, which
+	// it can't do when the upload is buffered before it is stored
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		_ = mw.WriteField("title", "notes")
+		part, _ := mw.CreateFormFile("file", "notes.txt")
+		_, _ = io.WriteString(part, content[:len(content)/2])
+		select {
+		case <-fs.received:
+		case <-time.After(5 * time.Second):
+			t.Error("the upload was buffered before it was stored")
+		}
+		_, _ = io.WriteString(part, content[len(content)/2:])
+		_ = pw.CloseWithError(mw.Close())
+	}()
+
+	r := httptest.NewRequest("POST", "/", pr)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+
+	uploaded, err := v.UploadFile(r, "notes", "file", fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Form.Get("title") != "notes" {
+		t.Errorf("expected the form values sent before the file, got %v", r.Form)
+	}
+
+	if !regexp.MustCompile(`^notes/[0-9a-f]{32}\.txt$`).MatchString(uploaded.Key) {
+		t.Errorf("expected a generated name with the extension of the type, got %s", uploaded.Key)
+	}
+	if string(fs.Content(uploaded.Key)) != content {
+		t.Errorf("expected all of the file to be stored, got %d bytes", len(fs.Content(uploaded.Key)))
+	}
+	sum := sha256.Sum256([]byte(content))
+	if uploaded.Size != int64(len(content)) || uploaded.Checksum != hex.EncodeToString(sum[:]) {
+		t.Errorf("wrong size or checksum: %d %s", uploaded.Size, uploaded.Checksum)
+	}
+	if uploaded.OriginalName != "notes.txt" || uploaded.MimeType != "text/plain; charset=utf-8" {
+		t.Errorf("wrong file details: %+v", uploaded)
+	}
+}
+
+func TestUpload_TooLarge(t *testing.T) {
+	v := newTestVelox()
+	v.config.uploads.maxUploadSize = 8 << 10
+	v.config.uploads.allowedMimeTypes = []string{"text/plain"}
+
+	tests := []struct {
+		name string
+		size int
+		err  error
+	}{
+		{"under the limit", 8 << 10, nil},
+		{"over the limit", 8<<10 + 1, ErrUploadTooLarge},
+		{"far over the limit", 1 << 20, ErrUploadTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs := &memfilesystem.Mem{}
+			dir := t.TempDir()
+			content := strings.Repeat("n", tt.size)
+
+			_, err := v.UploadFile(filesRequest(testFile{"file", "notes.txt", content}), "notes", "file", fs)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v storing on a filesystem, got %v", tt.err, err)
+			}
+			_, err = v.UploadFile(filesRequest(testFile{"file", "notes.txt", content}), dir, "file", nil)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("expected %v storing on local disk, got %v", tt.err, err)
+			}
+
+			stored := 0
+			if tt.err == nil {
+				stored = 1
+			}
+			files, _ := os.ReadDir(dir)
+			if len(fs.Keys()) != stored || len(files) != stored {
+				t.Errorf("expected %d files to be stored, got %v and %d local files", stored, fs.Keys(), len(files))
+			}
+		})
+	}
+}
+
+// pngFile returns a width by height PNG image
+func pngFile(t *testing.T, width, height int) string {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 255, A: 255})
+		}
+	}
+	buf := new(bytes.Buffer)
+	if err := png.Encode(buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestUpload_Images(t *testing.T) {
+	t.Setenv("IMAGE_VARIANTS", "thumb:10x10:crop")
+	t.Setenv("IMAGE_STRIP_ORIGINAL", "true")
+
+	v := newTestVelox()
+	v.config.uploads.maxUploadSize = 1 << 20
+	v.config.uploads.allowedMimeTypes = []string{"image/png"}
+
+	var err error
+	v.Images, err = createImages()
+	if err != nil || v.Images == nil {
+		t.Fatalf("expected a processor from the environment, got %v %v", v.Images, err)
+	}
+
+	fs := &memfilesystem.Mem{}
+	uploaded, err := v.UploadFile(filesRequest(testFile{"file", "photo.png", pngFile(t, 40, 20)}), "photos", "file", fs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, key := range map[string]string{"original": uploaded.Key, "thumb": uploaded.Variants["thumb"]} {
+		config, err := png.DecodeConfig(bytes.NewReader(fs.Content(key)))
+		if err != nil {
+			t.Fatalf("%s: expected an image at %q, got %v (%v)", name, key, err, fs.Keys())
+		}
+		expected := map[string][2]int{"original": {40, 20}, "thumb": {10, 10}}[name]
+		if config.Width != expected[0] || config.Height != expected[1] {
+			t.Errorf("%s: expected %v, got %dx%d", name, expected, config.Width, config.Height)
+		}
+	}
+	if uploaded.Variants["thumb"] != strings.TrimSuffix(uploaded.Key, ".png")+"_thumb.png" {
+		t.Errorf("expected the variant to be named after the image, got %v", uploaded.Variants)
+	}
+}
+
+func TestStoreImage_MaxSize(t *testing.T) {
+	data := pngFile(t, 40, 20)
+	processor := &images.Processor{Variants: []images.Variant{{Name: "thumb", Width: 10, Height: 10}}}
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		err     error
+	}{
+		{"within the limit", int64(len(data)), nil},
+		{"over the limit", int64(len(data)) - 1, ErrUploadTooLarge},
+		{"no limit", 0, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stored := 0
+			store := func(name string, src io.Reader, contentType string) (string, error) {
+				stored++
+				_, err := io.Copy(io.Discard, src)
+				return name, err
+			}
+
+			uploaded := &UploadedFile{MimeType: "image/png"}
+			err := storeImage(uploaded, strings.NewReader(data), "photo.png", tt.maxSize, processor, store)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+			if tt.err != nil && stored != 0 {
+				t.Errorf("expected nothing to be stored, got %d files", stored)
+			}
+			if tt.err == nil && (stored != 2 || uploaded.Size != int64(len(data))) {
+				t.Errorf("expected the image and its variant to be stored, got %d files of %d bytes", stored, uploaded.Size)
+			}
+		})
+	}
+}
+
+func TestCreateImages(t *testing.T) {
+	t.Setenv("IMAGE_VARIANTS", "")
+	if processor, err := createImages(); processor != nil || err != nil {
+		t.Errorf("expected no processor without IMAGE_VARIANTS, got %v %v", processor, err)
+	}
+
+	t.Setenv("IMAGE_VARIANTS", "thumb:150x150:crop")
+	t.Setenv("IMAGE_FORMAT", "WebP")
+	t.Setenv("IMAGE_QUALITY", "70")
+	t.Setenv("IMAGE_MAX_PIXELS", "1000000")
+	processor, err := createImages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(processor.Variants) != 1 || processor.Format != images.WebP || processor.Quality != 70 || processor.MaxPixels != 1000000 || processor.StripOriginal {
+		t.Errorf("expected the processor to follow the environment, got %+v", processor)
+	}
+
+	t.Setenv("IMAGE_FORMAT", "gif")
+	if _, err := createImages(); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	t.Setenv("IMAGE_FORMAT", "")
+	t.Setenv("IMAGE_VARIANTS", "thumb")
+	if _, err := createImages(); err == nil {
+		t.Error("expected an error for an invalid variant")
+	}
+}
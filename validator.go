@@ -1,25 +1,63 @@
 package velox
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
+	"unicode/utf8"
 
+	"github.com/FernandoJVideira/velox/i18n"
 	"github.com/asaskevich/govalidator"
 )
 
 type Validation struct {
-	Data   url.Values
-	Errors map[string]string
+	Data       url.Values
+	Errors     map[string]string
+	Locale     string
+	Translator *i18n.Translator
+	DB         Database
 }
 
 func (v *Velox) Validator(data url.Values) *Validation {
-	return &Validation{
-		Data:   data,
-		Errors: make(map[string]string),
+	validation := &Validation{
+		Data:       data,
+		Errors:     make(map[string]string),
+		Translator: v.Translator,
+		DB:         v.DB,
 	}
+	if v.Translator != nil {
+		validation.Locale = v.Translator.DefaultLocale
+	}
+	return validation
+}
+
+// ValidatorFor returns a validator for the form of a request, with error messages
+// translated into the locale of the request
+func (v *Velox) ValidatorFor(r *http.Request) *Validation {
+	validation := v.Validator(r.Form)
+	if locale := i18n.LocaleFromContext(r.Context()); locale != "" {
+		validation.Locale = locale
+	}
+	return validation
+}
+
+// message returns the translation of key (e.g. validation.required) for the validator's
+// locale, or fallback when there is none
+func (v *Validation) message(key, fallback string, args ...interface{}) string {
+	if v.Translator != nil {
+		if text, ok := v.Translator.Translate(v.Locale, key, args...); ok {
+			return text
+		}
+	}
+	return fallback
 }
 
 func (v *Validation) Valid() bool {
@@ -44,7 +82,7 @@ func (v *Validation) Required(r *http.Request, fields ...string) {
 	for _, field := range fields {
 		val := r.Form.Get(field)
 		if strings.TrimSpace(val) == "" {
-			v.AddError(field, "This field is required")
+			v.AddError(field, v.message("validation.required", "This field is required"))
 		}
 	}
 }
@@ -57,33 +95,250 @@ func (v *Validation) Check(ok bool, key, message string) {
 
 func (v *Validation) IsEmail(field, val string) {
 	if !govalidator.IsEmail(val) {
-		v.AddError(field, "Invalid email address")
+		v.AddError(field, v.message("validation.email", "Invalid email address"))
 	}
 }
 
 func (v *Validation) IsInt(field, val string) {
 	_, err := strconv.Atoi(val)
 	if err != nil {
-		v.AddError(field, "This field must be an integer")
+		v.AddError(field, v.message("validation.int", "This field must be an integer"))
 	}
 }
 
 func (v *Validation) IsFloat(field, val string) {
 	_, err := strconv.ParseFloat(val, 64)
 	if err != nil {
-		v.AddError(field, "This field must be a floating point number")
+		v.AddError(field, v.message("validation.float", "This field must be a floating point number"))
 	}
 }
 
-func (v *Validation) IsDate(field, val string) {
-	_, err := time.Parse("02-01-2006", val)
-	if err != nil {
-		v.AddError(field, "This field must be a date in the format DD-MM-YYYY")
+// IsDate checks that val is a date in one of layouts, or in the format DD-MM-YYYY when no
+// layout is given
+func (v *Validation) IsDate(field, val string, layouts ...string) {
+	if len(layouts) == 0 {
+		layouts = []string{"02-01-2006"}
+	}
+	if _, ok := parseTime(val, layouts); !ok {
+		v.AddError(field, v.message("validation.date", "This field must be a date in the format "+dateFormat(layouts[0]), "format", dateFormat(layouts[0])))
 	}
 }
 
 func (v *Validation) NoSpaces(field, val string) {
 	if govalidator.HasWhitespace(val) {
-		v.AddError(field, "This field must not contain any spaces")
+		v.AddError(field, v.message("validation.no_spaces", "This field must not contain any spaces"))
+	}
+}
+
+// MinLength checks that val has at least min characters
+func (v *Validation) MinLength(field, val string, min int) {
+	if utf8.RuneCountInString(val) < min {
+		v.AddError(field, v.message("validation.min_length", fmt.Sprintf("This field must be at least %d characters long", min), "min", min))
+	}
+}
+
+// MaxLength checks that val has at most max characters
+func (v *Validation) MaxLength(field, val string, max int) {
+	if utf8.RuneCountInString(val) > max {
+		v.AddError(field, v.message("validation.max_length", fmt.Sprintf("This field must be at most %d characters long", max), "max", max))
+	}
+}
+
+// MinValue checks that val is a number no smaller than min
+func (v *Validation) MinValue(field, val string, min float64) {
+	n, err := strconv.ParseFloat(val, 64)
+	if err != nil || n < min {
+		v.AddError(field, v.message("validation.min", fmt.Sprintf("This field must be at least %g", min), "min", min))
+	}
+}
+
+// MaxValue checks that val is a number no greater than max
+func (v *Validation) MaxValue(field, val string, max float64) {
+	n, err := strconv.ParseFloat(val, 64)
+	if err != nil || n > max {
+		v.AddError(field, v.message("validation.max", fmt.Sprintf("This field must be at most %g", max), "max", max))
+	}
+}
+
+// Between checks that val is a number from min to max, inclusive
+func (v *Validation) Between(field, val string, min, max float64) {
+	n, err := strconv.ParseFloat(val, 64)
+	if err != nil || n < min || n > max {
+		v.AddError(field, v.message("validation.between", fmt.Sprintf("This field must be between %g and %g", min, max), "min", min, "max", max))
+	}
+}
+
+// Matches checks that val matches pattern
+func (v *Validation) Matches(field, val string, pattern *regexp.Regexp) {
+	if !pattern.MatchString(val) {
+		v.AddError(field, v.message("validation.regex", "This field has an invalid format"))
+	}
+}
+
+func (v *Validation) IsURL(field, val string) {
+	if !isURL(val) {
+		v.AddError(field, v.message("validation.url", "This field must be a valid URL"))
+	}
+}
+
+func (v *Validation) IsUUID(field, val string) {
+	if !govalidator.IsUUID(val) {
+		v.AddError(field, v.message("validation.uuid", "This field must be a valid UUID"))
+	}
+}
+
+func (v *Validation) IsIP(field, val string) {
+	if net.ParseIP(val) == nil {
+		v.AddError(field, v.message("validation.ip", "This field must be a valid IP address"))
+	}
+}
+
+func (v *Validation) IsAlphaNumeric(field, val string) {
+	if !govalidator.IsAlphanumeric(val) {
+		v.AddError(field, v.message("validation.alphanum", "This field may only contain letters and numbers"))
+	}
+}
+
+// In checks that val is one of options
+func (v *Validation) In(field, val string, options ...string) {
+	if !inSlice(options, val) {
+		values := strings.Join(options, ", ")
+		v.AddError(field, v.message("validation.oneof", "This field must be one of: "+values, "values", values))
+	}
+}
+
+// NotIn checks that val is none of options
+func (v *Validation) NotIn(field, val string, options ...string) {
+	if inSlice(options, val) {
+		v.AddError(field, v.message("validation.notin", "This value is not allowed"))
 	}
 }
+
+// Same checks that two fields of the data hold the same value, adding the error to field
+func (v *Validation) Same(field, other string) {
+	if v.Data.Get(field) != v.Data.Get(other) {
+		v.AddError(field, v.message("validation.same", "This field must match "+other, "other", other))
+	}
+}
+
+// Confirmed checks that field matches its confirmation, e.g. password and password_confirmation
+func (v *Validation) Confirmed(field string) {
+	if v.Data.Get(field) != v.Data.Get(field+"_confirmation") {
+		v.AddError(field, v.message("validation.confirmed", "The confirmation does not match"))
+	}
+}
+
+// StrongPassword checks that val is at least minLength characters long and mixes lower and
+// upper case letters, numbers and symbols
+func (v *Validation) StrongPassword(field, val string, minLength int) {
+	if !isStrongPassword(val, minLength) {
+		v.AddError(field, v.message("validation.password",
+			fmt.Sprintf("The password must be at least %d characters long and contain upper and lower case letters, numbers and symbols", minLength),
+			"min", minLength))
+	}
+}
+
+// Before checks that val, a date in layout, is before t
+func (v *Validation) Before(field, val, layout string, t time.Time) {
+	date, ok := parseTime(val, []string{layout})
+	if !ok || !date.Before(t) {
+		v.AddError(field, v.message("validation.before", "This field must be a date before "+t.Format(layout), "date", t.Format(layout)))
+	}
+}
+
+// After checks that val, a date in layout, is after t
+func (v *Validation) After(field, val, layout string, t time.Time) {
+	date, ok := parseTime(val, []string{layout})
+	if !ok || !date.After(t) {
+		v.AddError(field, v.message("validation.after", "This field must be a date after "+t.Format(layout), "date", t.Format(layout)))
+	}
+}
+
+// Unique checks that no row of table has val in column, as for registration emails. The
+// error is only set when the database can't be queried
+func (v *Validation) Unique(field, val, table, column string) error {
+	count, err := v.count(table, column, val)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		v.AddError(field, v.message("validation.unique", "This value has already been taken"))
+	}
+	return nil
+}
+
+// Exists checks that some row of table has val in column, as for foreign keys. The error is
+// only set when the database can't be queried
+func (v *Validation) Exists(field, val, table, column string) error {
+	count, err := v.count(table, column, val)
+	if err != nil {
+		return err
+	}
+	if count == 0 {
+		v.AddError(field, v.message("validation.exists", "The selected value is invalid"))
+	}
+	return nil
+}
+
+// count returns how many rows of table have val in column
+func (v *Validation) count(table, column string, val interface{}) (int, error) {
+	if v.DB.Pool == nil {
+		return 0, errors.New("validation: no database connection")
+	}
+	if !sqlIdentifier.MatchString(table) || !sqlIdentifier.MatchString(column) {
+		return 0, fmt.Errorf("validation: invalid table or column name %s.%s", table, column)
+	}
+
+	placeholder := "?"
+	switch strings.ToLower(v.DB.DbType) {
+	case "postgres", "postgresql", "pgx":
+		placeholder = "$1"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var count int
+	query := fmt.Sprintf("select count(*) from %s where %s = %s", table, column, placeholder)
+	err := v.DB.Pool.QueryRowContext(ctx, query, val).Scan(&count)
+	return count, err
+}
+
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+func isURL(val string) bool {
+	u, err := url.ParseRequestURI(val)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func isStrongPassword(val string, minLength int) bool {
+	var lower, upper, digit, symbol bool
+	for _, r := range val {
+		switch {
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r), unicode.IsSymbol(r), unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	return utf8.RuneCountInString(val) >= minLength && lower && upper && digit && symbol
+}
+
+// parseTime parses val with the first of layouts that fits it
+func parseTime(val string, layouts []string) (time.Time, bool) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, strings.TrimSpace(val)); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// dateFormat turns a Go time layout into the format users know, e.g. 02-01-2006 into DD-MM-YYYY
+func dateFormat(layout string) string {
+	return strings.NewReplacer("2006", "YYYY", "01", "MM", "02", "DD", "15", "HH", "04", "mm", "05", "ss").Replace(layout)
+}
@@ -0,0 +1,177 @@
+package velox
+
+import (
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/FernandoJVideira/velox/i18n"
+)
+
+func TestValidation_Checks(t *testing.T) {
+	jan1 := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	data := url.Values{
+		"password":              {"secret"},
+		"password_confirmation": {"other"},
+		"email":                 {"a@b.c"},
+		"email_confirmation":    {"a@b.c"},
+		"repeat":                {"a@b.c"},
+	}
+
+	tests := []struct {
+		name  string
+		check func(v *Validation, val string)
+		valid []string
+		wrong []string
+	}{
+		{"IsEmail", func(v *Validation, val string) { v.IsEmail("f", val) },
+			[]string{"me@here.com"}, []string{"", "me@", "here.com"}},
+		{"IsInt", func(v *Validation, val string) { v.IsInt("f", val) },
+			[]string{"1", "-20"}, []string{"", "1.5", "one"}},
+		{"IsFloat", func(v *Validation, val string) { v.IsFloat("f", val) },
+			[]string{"1", "1.5", "-2e3"}, []string{"", "one"}},
+		{"IsDate", func(v *Validation, val string) { v.IsDate("f", val) },
+			[]string{"29-02-2024"}, []string{"2024-02-29", "30-02-2024"}},
+		{"IsDate layouts", func(v *Validation, val string) { v.IsDate("f", val, "2006-01-02", "02/01/2006") },
+			[]string{"2024-02-29", "29/02/2024"}, []string{"29-02-2024"}},
+		{"NoSpaces", func(v *Validation, val string) { v.NoSpaces("f", val) },
+			[]string{"ana"}, []string{"a na", "ana\t"}},
+		{"MinLength", func(v *Validation, val string) { v.MinLength("f", val, 3) },
+			[]string{"abc", "çãó"}, []string{"ab"}},
+		{"MaxLength", func(v *Validation, val string) { v.MaxLength("f", val, 3) },
+			[]string{"abc", "çãó"}, []string{"abcd"}},
+		{"MinValue", func(v *Validation, val string) { v.MinValue("f", val, 18) },
+			[]string{"18", "20.5"}, []string{"17.9", "old"}},
+		{"MaxValue", func(v *Validation, val string) { v.MaxValue("f", val, 10) },
+			[]string{"10", "-1"}, []string{"10.1", "ten"}},
+		{"Between", func(v *Validation, val string) { v.Between("f", val, 1, 5) },
+			[]string{"1", "5"}, []string{"0", "6", "x"}},
+		{"Matches", func(v *Validation, val string) { v.Matches("f", val, regexp.MustCompile(`^\d{2,4}$`)) },
+			[]string{"12", "1234"}, []string{"1", "12345"}},
+		{"IsURL", func(v *Validation, val string) { v.IsURL("f", val) },
+			[]string{"https://example.com/a?b=c", "http://localhost:4000"}, []string{"example.com", "ftp://example.com", "/path"}},
+		{"IsUUID", func(v *Validation, val string) { v.IsUUID("f", val) },
+			[]string{"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, []string{"6ba7b810"}},
+		{"IsIP", func(v *Validation, val string) { v.IsIP("f", val) },
+			[]string{"127.0.0.1", "::1"}, []string{"localhost", "1.2.3"}},
+		{"IsAlphaNumeric", func(v *Validation, val string) { v.IsAlphaNumeric("f", val) },
+			[]string{"abc123"}, []string{"abc_123", "a b"}},
+		{"In", func(v *Validation, val string) { v.In("f", val, "a", "b") },
+			[]string{"a", "b"}, []string{"c", ""}},
+		{"NotIn", func(v *Validation, val string) { v.NotIn("f", val, "root") },
+			[]string{"ana", ""}, []string{"root"}},
+		{"StrongPassword", func(v *Validation, val string) { v.StrongPassword("f", val, 8) },
+			[]string{"Secret-123", "Pass word1"}, []string{"Secre-1", "secret-123", "SECRET-123", "Secret123", "Secret-abc"}},
+		{"Before", func(v *Validation, val string) { v.Before("f", val, "2006-01-02", jan1) },
+			[]string{"2023-12-31"}, []string{"2024-01-01", "yesterday"}},
+		{"After", func(v *Validation, val string) { v.After("f", val, "2006-01-02", jan1) },
+			[]string{"2024-01-02"}, []string{"2024-01-01", "tomorrow"}},
+		{"Check", func(v *Validation, val string) { v.Check(val == "yes", "f", "must be yes") },
+			[]string{"yes"}, []string{"no"}},
+		{"Same", func(v *Validation, val string) { v.Same(val, "repeat") },
+			[]string{"email"}, []string{"password"}},
+		{"Confirmed", func(v *Validation, val string) { v.Confirmed(val) },
+			[]string{"email"}, []string{"password"}},
+	}
+
+	v := newTestVelox()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, val := range tt.valid {
+				validation := v.Validator(data)
+				tt.check(validation, val)
+				if !validation.Valid() {
+					t.Errorf("expected %q to be valid, got %v", val, validation.Errors)
+				}
+			}
+			for _, val := range tt.wrong {
+				validation := v.Validator(data)
+				tt.check(validation, val)
+				if validation.Valid() {
+					t.Errorf("expected %q to be invalid", val)
+				}
+			}
+		})
+	}
+}
+
+func TestValidation_Required(t *testing.T) {
+	r := httptest.NewRequest("POST", "/", strings.NewReader("name=ana&blank=+&email="))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	_ = r.ParseForm()
+
+	validation := newTestVelox().ValidatorFor(r)
+	validation.Required(r, "name", "blank", "email", "missing")
+
+	for _, field := range []string{"blank", "email", "missing"} {
+		if validation.Errors[field] == "" {
+			t.Errorf("expected %s to be required", field)
+		}
+	}
+	if _, ok := validation.Errors["name"]; ok {
+		t.Error("expected name to be valid")
+	}
+	if !validation.Has("name", r) || validation.Has("email", r) {
+		t.Error("Has should only report fields with a value")
+	}
+}
+
+func TestValidation_FirstErrorWins(t *testing.T) {
+	validation := newTestVelox().Validator(nil)
+	validation.AddError("name", "first")
+	validation.AddError("name", "second")
+
+	if validation.Errors["name"] != "first" {
+		t.Errorf("expected the first error to be kept, got %q", validation.Errors["name"])
+	}
+}
+
+func TestValidation_Translated(t *testing.T) {
+	translator := i18n.New("en")
+	translator.Add("pt", map[string]interface{}{
+		"validation": map[string]interface{}{
+			"required":   "Campo obrigatório",
+			"min_length": "Mínimo de {min} caracteres",
+		},
+	})
+
+	v := newTestVelox()
+	v.Translator = translator
+
+	r := httptest.NewRequest("POST", "/", nil)
+	r = r.WithContext(i18n.WithLocale(r.Context(), "pt"))
+	_ = r.ParseForm()
+
+	validation := v.ValidatorFor(r)
+	validation.Required(r, "name")
+	validation.MinLength("nick", "a", 3)
+	validation.IsEmail("email", "nope")
+
+	expected := map[string]string{
+		"name":  "Campo obrigatório",
+		"nick":  "Mínimo de 3 caracteres",
+		"email": "Invalid email address",
+	}
+	for field, message := range expected {
+		if validation.Errors[field] != message {
+			t.Errorf("%s: expected %q, got %q", field, message, validation.Errors[field])
+		}
+	}
+}
+
+func TestValidation_DatabaseErrors(t *testing.T) {
+	validation := newTestVelox().Validator(nil)
+
+	if err := validation.Unique("email", "a@b.c", "users", "email"); err == nil {
+		t.Error("expected an error from Unique without a database")
+	}
+	if err := validation.Exists("user_id", "1", "users", "id"); err == nil {
+		t.Error("expected an error from Exists without a database")
+	}
+	if !validation.Valid() {
+		t.Errorf("database errors should not be reported as validation errors, got %v", validation.Errors)
+	}
+}
@@ -10,10 +10,15 @@ import (
 	"strings"
 	"time"
 
+	"github.com/FernandoJVideira/velox/clamav"
+	"github.com/FernandoJVideira/velox/filesystems"
+	"github.com/FernandoJVideira/velox/filesystems/localfilesystem"
 	"github.com/FernandoJVideira/velox/filesystems/miniofilesystem"
 	"github.com/FernandoJVideira/velox/filesystems/s3filesystem"
 	"github.com/FernandoJVideira/velox/filesystems/sftpfilesystem"
 	"github.com/FernandoJVideira/velox/filesystems/webdavfilesystem"
+	"github.com/FernandoJVideira/velox/i18n"
+	"github.com/FernandoJVideira/velox/images"
 	"github.com/FernandoJVideira/velox/mailer"
 
 	"github.com/dgraph-io/badger/v3"
@@ -58,11 +63,21 @@ type Velox struct {
 	Scheduler     *cron.Cron
 	Mail          mailer.Mail
 	Server        Server
+	Disks         *filesystems.Disks
+	// FileSystems holds the S3, MINIO, SFTP, WEBDAV and LOCAL filesystems by value, as before disks.
+	//
+	// Deprecated: use v.Disk, or the S3, Minio, SFTP, WebDAV and Local fields
 	FileSystems   map[string]interface{}
 	S3            s3filesystem.S3
 	SFTP          sftpfilesystem.SFTP
 	WebDAV        webdavfilesystem.WebDAV
 	Minio         miniofilesystem.Minio
+	Local         localfilesystem.Local
+	Translator    *i18n.Translator
+	Images        *images.Processor
+	UploadScanner UploadScanner
+	csrfExempt    []string
+	fileRoutes    string
 }
 
 type Server struct {
@@ -80,6 +95,8 @@ type config struct {
 	database    dbConfig
 	redis       redisConfig
 	uploads     uploadConfig
+	// localePrefixes are the url prefixes DetectLocale reads the locale from, e.g. pt in /pt/about
+	localePrefixes []string
 }
 
 type uploadConfig struct {
@@ -93,7 +110,7 @@ func (v *Velox) New(rootPath string) error {
 	//Create folder structure if it doesn't exist
 	pathConfig := initPaths{
 		RootPath:    rootPath,
-		FolderNames: []string{"handlers", "migrations", "views", "mail", "data", "public", "tmp", "logs", "middleware", "screenshots"},
+		FolderNames: []string{"handlers", "migrations", "views", "mail", "data", "public", "tmp", "logs", "middleware", "screenshots", "lang"},
 	}
 
 	err := v.Init(pathConfig)
@@ -156,6 +173,21 @@ func (v *Velox) New(rootPath string) error {
 	v.Mail = v.createMailer()
 	v.Routes = v.routes().(*chi.Mux)
 
+	// Translations
+	v.Translator = i18n.New(os.Getenv("DEFAULT_LOCALE"))
+	err = v.Translator.LoadDir(rootPath + "/lang")
+	if err != nil {
+		return err
+	}
+	v.Mail.Translator = v.Translator
+
+	var localePrefixes []string
+	for _, prefix := range strings.Split(os.Getenv("LOCALE_URL_PREFIXES"), ",") {
+		if prefix = strings.Trim(strings.TrimSpace(prefix), "/"); prefix != "" {
+			localePrefixes = append(localePrefixes, prefix)
+		}
+	}
+
 	//File Uploads
 	exploded := strings.Split(os.Getenv("ALLOWED_FILETYPES"), ",")
 	var mimeTypes []string
@@ -167,21 +199,42 @@ func (v *Velox) New(rootPath string) error {
 	var maxUploadSize int64
 
 	if max, err := strconv.Atoi(os.Getenv("MAX_UPLOAD_SIZE")); err != nil {
-		maxUploadSize = 10 << 20
+		maxUploadSize = defaultMaxUploadSize
 	} else {
 		maxUploadSize = int64(max)
 	}
 
+	if os.Getenv("CLAMD_ADDRESS") != "" {
+		v.UploadScanner = &clamav.Client{Address: os.Getenv("CLAMD_ADDRESS")}
+	}
+
+	v.Images, err = createImages()
+	if err != nil {
+		return err
+	}
+
+	// new apps set COOKIE_PERSIST, which older versions read as COOKIE_PERSISTS
+	cookiePersist := os.Getenv("COOKIE_PERSIST")
+	if cookiePersist == "" {
+		cookiePersist = os.Getenv("COOKIE_PERSISTS")
+	}
+
 	// Set config
 	v.config = config{
 		port:     os.Getenv("PORT"),
 		renderer: os.Getenv("RENDERER"),
 		cookie: cookieConfig{
-			name:     os.Getenv("COOKIE_NAME"),
-			lifetime: os.Getenv("COOKIE_LIFETIME"),
-			persist:  os.Getenv("COOKIE_PERSISTS"),
-			secure:   os.Getenv("COOKIE_SECURE"),
-			domain:   os.Getenv("COOKIE_DOMAIN"),
+			name:         os.Getenv("COOKIE_NAME"),
+			lifetime:     os.Getenv("COOKIE_LIFETIME"),
+			idleTimeout:  os.Getenv("SESSION_IDLE_TIMEOUT"),
+			persist:      cookiePersist,
+			secure:       os.Getenv("COOKIE_SECURE"),
+			domain:       os.Getenv("COOKIE_DOMAIN"),
+			path:         os.Getenv("COOKIE_PATH"),
+			sameSite:     os.Getenv("COOKIE_SAMESITE"),
+			csrfSameSite: os.Getenv("CSRF_COOKIE_SAMESITE"),
+			httpOnly:     os.Getenv("COOKIE_HTTPONLY"),
+			partitioned:  os.Getenv("COOKIE_PARTITIONED"),
 		},
 		sessionType: os.Getenv("SESSION_TYPE"),
 		database: dbConfig{
@@ -197,6 +250,7 @@ func (v *Velox) New(rootPath string) error {
 			maxUploadSize:    maxUploadSize,
 			allowedMimeTypes: mimeTypes,
 		},
+		localePrefixes: localePrefixes,
 	}
 
 	// Server config
@@ -215,11 +269,17 @@ func (v *Velox) New(rootPath string) error {
 	// create session
 
 	sess := session.Session{
-		CookieLifetime: v.config.cookie.lifetime,
-		CookiePersist:  v.config.cookie.persist,
-		CookieName:     v.config.cookie.name,
-		SessionType:    v.config.sessionType,
-		CookieDomain:   v.config.cookie.domain,
+		CookieLifetime:    v.config.cookie.lifetime,
+		IdleTimeout:       v.config.cookie.idleTimeout,
+		CookiePersist:     v.config.cookie.persist,
+		CookieName:        v.config.cookie.name,
+		SessionType:       v.config.sessionType,
+		CookieDomain:      v.config.cookie.domain,
+		CookieSecure:      v.config.cookie.secure,
+		CookiePath:        v.config.cookie.path,
+		CookieSameSite:    v.config.cookie.sameSite,
+		CookieHTTPOnly:    v.config.cookie.httpOnly,
+		CookiePartitioned: v.config.cookie.partitioned,
 	}
 
 	switch v.config.sessionType {
@@ -250,7 +310,11 @@ func (v *Velox) New(rootPath string) error {
 
 	// Create renderer
 	v.CreateRenderer()
-	v.FileSystems = v.createFileSystems()
+	v.Mail.Renderer = v.Render
+	v.Disks, err = v.createDisks()
+	if err != nil {
+		return err
+	}
 	go v.Mail.ListenForMail()
 
 	return nil
@@ -292,15 +356,48 @@ func (v *Velox) startLoggers() (*log.Logger, *log.Logger) {
 // CreateRenderer creates the renderer
 func (v *Velox) CreateRenderer() {
 	rend := render.Render{
-		Renderer: v.config.renderer,
-		RootPath: v.RootPath,
-		Port:     v.config.port,
-		JetViews: v.JetViews,
-		Session:  v.Session,
+		Renderer:   v.config.renderer,
+		RootPath:   v.RootPath,
+		Port:       v.config.port,
+		URL:        v.Server.URL,
+		Debug:      v.Debug,
+		JetViews:   v.JetViews,
+		Session:    v.Session,
+		Translator: v.Translator,
 	}
+	rend.AddBuiltins()
 	v.Render = &rend
 }
 
+// createImages returns the processor that makes the variants of uploaded images, set up by
+// IMAGE_VARIANTS, IMAGE_FORMAT, IMAGE_QUALITY, IMAGE_STRIP_ORIGINAL and IMAGE_MAX_PIXELS, or nil
+// when IMAGE_VARIANTS is empty
+func createImages() (*images.Processor, error) {
+	variants, err := images.ParseVariants(os.Getenv("IMAGE_VARIANTS"))
+	if err != nil || len(variants) == 0 {
+		return nil, err
+	}
+
+	format := strings.ToLower(os.Getenv("IMAGE_FORMAT"))
+	switch format {
+	case "", images.JPEG, images.PNG, images.WebP:
+	default:
+		return nil, fmt.Errorf("images: IMAGE_FORMAT must be jpeg, png or webp, not %q", format)
+	}
+
+	quality, _ := strconv.Atoi(os.Getenv("IMAGE_QUALITY"))
+	maxPixels, _ := strconv.Atoi(os.Getenv("IMAGE_MAX_PIXELS"))
+	stripOriginal, _ := strconv.ParseBool(os.Getenv("IMAGE_STRIP_ORIGINAL"))
+
+	return &images.Processor{
+		Variants:      variants,
+		Format:        format,
+		Quality:       quality,
+		StripOriginal: stripOriginal,
+		MaxPixels:     maxPixels,
+	}, nil
+}
+
 func (v *Velox) createMailer() mailer.Mail {
 	port, _ := strconv.Atoi(os.Getenv("SMTP_PORT"))
 	m := mailer.Mail{
@@ -398,64 +495,6 @@ func (v *Velox) BuildDSN() string {
 	return dsn
 }
 
-func (v *Velox) createFileSystems() map[string]interface{} {
-	fileSystems := make(map[string]interface{})
-
-	if os.Getenv("MINIO_SECRET") != "" {
-		useSSL := false
-		if strings.ToLower(os.Getenv("MINIO_USESSL")) == "true" {
-			useSSL = true
-		}
-
-		minio := miniofilesystem.Minio{
-			Endpoint: os.Getenv("MINIO_ENDPOINT"),
-			Key:      os.Getenv("MINIO_KEY"),
-			Secret:   os.Getenv("MINIO_SECRET"),
-			UseSSL:   useSSL,
-			Region:   os.Getenv("MINIO_REGION"),
-			Bucket:   os.Getenv("MINIO_BUCKET"),
-		}
-
-		fileSystems["MINIO"] = minio
-		v.Minio = minio
-	}
-
-	if os.Getenv("SFTP_HOST") != "" {
-		sftp := sftpfilesystem.SFTP{
-			Host: os.Getenv("SFTP_HOST"),
-			User: os.Getenv("SFTP_USER"),
-			Pass: os.Getenv("SFTP_PASS"),
-			Port: os.Getenv("SFTP_PORT"),
-		}
-		fileSystems["SFTP"] = sftp
-		v.SFTP = sftp
-	}
-
-	if os.Getenv("WEBDAV_HOST") != "" {
-		webDav := webdavfilesystem.WebDAV{
-			Host: os.Getenv("WEBDAV_HOST"),
-			User: os.Getenv("WEBDAV_USER"),
-			Pass: os.Getenv("WEBDAV_PASS"),
-		}
-		fileSystems["WEBDAV"] = webDav
-		v.WebDAV = webDav
-	}
-
-	if os.Getenv("S3_KEY") != "" {
-		s3 := s3filesystem.S3{
-			Key:      os.Getenv("S3_KEY"),
-			Secret:   os.Getenv("S3_SECRET"),
-			Region:   os.Getenv("S3_REGION"),
-			Endpoint: os.Getenv("S3_ENDPOINT"),
-			Bucket:   os.Getenv("S3_BUCKET"),
-		}
-		fileSystems["S3"] = s3
-		v.S3 = s3
-	}
-
-	return fileSystems
-}
-
 type RPCServer struct{}
 
 func (r *RPCServer) MaintenanceMode(inMaintenanceMode bool, resp *string) error {